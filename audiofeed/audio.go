@@ -0,0 +1,127 @@
+/** Author: Charney Kaye */
+
+// Package audiofeed follows the music analysed by wav_store -analysis
+package audiofeed
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/charneykaye/go-SDL-experiements/linefeed"
+	"math"
+)
+
+var (
+	BufferSize int     = 256 // analyses waiting to be read
+	BeatMs     float64 = 250 // for the pulse of a beat to fade
+	BeatKick   float64 = 0.5 // added to the level at a beat
+)
+
+/* the music is heard through the
+ █████╗ ██╗   ██╗██████╗ ██╗ ██████╗
+██╔══██╗██║   ██║██╔══██╗██║██╔═══██╗
+███████║██║   ██║██║  ██║██║██║   ██║
+██╔══██║██║   ██║██║  ██║██║██║   ██║
+██║  ██║╚██████╔╝██████╔╝██║╚██████╔╝
+╚═╝  ╚═╝ ╚═════╝ ╚═════╝ ╚═╝ ╚═════╝*/
+
+// Analysis is one line of what wav_store sends with -analysis, e.g.
+//
+//	{"t":12.35,"rms":0.21,"level":0.77,"bands":[0.81,0.74,0.62,0.55,0.41,0.2],"flux":3.1,"onset":true,"beat":true,"bpm":124}
+//
+// Levels are from 0 to 1, and bands go from low to high.
+type Analysis struct {
+	Time  float64   `json:"t"`     // seconds since the start of the stream
+	RMS   float64   `json:"rms"`   // of every sample
+	Level float64   `json:"level"` // RMS in dB, from -60 to 0 as 0 to 1
+	Bands []float64 `json:"bands"` // the same, of the energy in each band
+	Flux  float64   `json:"flux"`  // dB the spectrum rose by, on average
+	Onset bool      `json:"onset"` // something started
+	Beat  bool      `json:"beat"`  // something low started
+	BPM   float64   `json:"bpm,omitempty"`
+}
+
+// Open starts reading analyses from src, which is "-" for stdin,
+// "udp://host:port" or "tcp://host:port" for a local socket, or else the path
+// of a file.
+func Open(src string) (<-chan Analysis, error) {
+	ch := make(chan Analysis, BufferSize)
+	err := linefeed.Read(src, "Audio", func(line []byte) {
+		var a Analysis
+		if err := json.Unmarshal(line, &a); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"line":  string(line),
+			}).Warn("Skipped bad audio line")
+			return
+		}
+		ch <- a
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+func NewAudio(feed <-chan Analysis) *Audio {
+	return &Audio{feed: feed}
+}
+
+// Audio follows the music as it plays: how loud it is, overall and in each
+// band, and a pulse on each beat that fades over BeatMs
+type Audio struct {
+	Level float64
+	Bands []float64
+	Beat  float64 // 1 on a beat, fading to 0
+	BPM   float64 // 0 until the tempo is known
+	/* private */
+	feed   <-chan Analysis
+	lastMs uint32
+}
+
+// Life fades the beat, and drains the feed without blocking
+func (a *Audio) Life(nowMs uint32) {
+	if a.lastMs != 0 {
+		a.Beat = math.Max(0, a.Beat-float64(nowMs-a.lastMs)/BeatMs)
+	}
+	a.lastMs = nowMs
+	for received := true; received; {
+		select {
+		case an := <-a.feed:
+			a.Level, a.Bands = an.Level, an.Bands
+			if an.BPM > 0 {
+				a.BPM = an.BPM
+			}
+			if an.Beat {
+				a.Beat = 1
+			}
+		default:
+			received = false
+		}
+	}
+}
+
+// Lows is the level of the lower half of the bands
+func (a *Audio) Lows() float64 {
+	return a.bandLevel(0, len(a.Bands)/2)
+}
+
+// Highs is the level of the upper half of the bands
+func (a *Audio) Highs() float64 {
+	return a.bandLevel(len(a.Bands)/2, len(a.Bands))
+}
+
+func (a *Audio) bandLevel(from, to int) float64 {
+	if to <= from {
+		return a.Level
+	}
+	sum := 0.0
+	for _, b := range a.Bands[from:to] {
+		sum += b
+	}
+	return sum / float64(to-from)
+}
+
+// Kicked is a level kicked up by BeatKick as a beat fades, up to 1
+func (a *Audio) Kicked(level float64) float64 {
+	return math.Min(1, level+BeatKick*a.Beat)
+}
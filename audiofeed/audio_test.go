@@ -0,0 +1,87 @@
+/** Author: Charney Kaye */
+
+package audiofeed
+
+import (
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testLine = `{"t":1,"rms":0.2,"level":0.6,"bands":[0.9,0.8,0.7,0.1,0.2,0.3],"flux":1,"onset":true,"beat":true,"bpm":120}`
+
+// follow the feed until it has a level, or a second has gone by
+func follow(t *testing.T, feed <-chan Analysis) *Audio {
+	a := NewAudio(feed)
+	deadline := time.Now().Add(time.Second)
+	for a.Level == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		a.Life(1000)
+	}
+	if a.Level != 0.6 || a.Beat != 1 || a.BPM != 120 {
+		t.Fatalf("followed %+v", a)
+	}
+	return a
+}
+
+func TestOpen(t *testing.T) {
+	// ask the OS for a free port, then send to it
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := conn.LocalAddr().String()
+	conn.Close()
+	feed, err := Open("udp://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	// two lines to a datagram, one of them bad
+	if _, err := sender.Write([]byte("bad\n" + testLine + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	follow(t, feed)
+
+	path := filepath.Join(t.TempDir(), "analysis.json")
+	if err := os.WriteFile(path, []byte("\n"+testLine+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if feed, err = Open(path); err != nil {
+		t.Fatal(err)
+	}
+	follow(t, feed)
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("opened a missing file")
+	}
+}
+
+func TestAudio(t *testing.T) {
+	feed := make(chan Analysis, 1)
+	feed <- Analysis{Level: 0.6, Bands: []float64{0.9, 0.8, 0.7, 0.1, 0.2, 0.3}, Beat: true, BPM: 120}
+	a := follow(t, feed)
+	if math.Abs(a.Lows()-0.8) > 1e-9 || math.Abs(a.Highs()-0.2) > 1e-9 {
+		t.Errorf("lows %g and highs %g", a.Lows(), a.Highs())
+	}
+	// half of BeatMs later
+	a.Life(1000 + uint32(BeatMs/2))
+	if a.Beat != 0.5 || a.Kicked(0.6) != 0.6+BeatKick/2 || a.Kicked(0.9) != 1 {
+		t.Errorf("beat %g kicks 0.6 to %g", a.Beat, a.Kicked(0.6))
+	}
+	a.Life(2000)
+	if a.Beat != 0 || a.BPM != 120 {
+		t.Errorf("beat %g at %g BPM, after it's faded", a.Beat, a.BPM)
+	}
+	// without bands, they're all the level
+	b := &Audio{Level: 0.3}
+	if b.Lows() != 0.3 || b.Highs() != 0.3 {
+		t.Errorf("lows %g and highs %g of level 0.3", b.Lows(), b.Highs())
+	}
+}
@@ -3,7 +3,9 @@
 package main
 
 import (
+	"flag"
 	log "github.com/Sirupsen/logrus"
+	"github.com/charneykaye/go-SDL-experiements/audiofeed"
 	"github.com/veandco/go-sdl2/sdl"
 	"math"
 	"math/rand"
@@ -16,6 +18,8 @@ var (
 	firePointSize         int     = 3
 	fireGenRows           int     = 2
 	fireDecay             float64 = 0.98
+	fireAudioFloor        float64 = 0.1 // of the flames left when the music is quiet
+	audioSource           string        // empty for none, see audiofeed.Open
 )
 
 /* the raster is in a
@@ -33,8 +37,10 @@ func NewFire() *Fire {
 }
 
 type Fire struct {
+	Audio *audiofeed.Audio // nil unless the flames follow music
 	/* private */
-	Points [][]float64
+	Points    [][]float64
+	intensity float64 // of the music, this frame
 }
 
 func (r *Fire) Initialize() {
@@ -46,6 +52,10 @@ func (r *Fire) Initialize() {
 }
 
 func (r *Fire) RenderToSurface(surface *sdl.Surface) {
+	if r.Audio != nil {
+		r.Audio.Life(sdl.GetTicks())
+		r.intensity = r.Audio.Kicked(r.Audio.Lows())
+	}
 	sBox := sdl.Rect{0, 0, int32(firePointSize), int32(firePointSize)}
 	for y := 0; y < fireHeight-2; y++ {
 		sBox.Y = int32(y * firePointSize)
@@ -85,7 +95,12 @@ func (r *Fire) PointSeek(y int, x int) float64 {
 func (r *Fire) PointBirth(y int, x int) {
 	// bottom row generates pixels that are on/off
 	// chance of being on (c) is inversely proportional to distance from center
-	if rand.Float64() < 1-math.Abs(float64(x-fireCenterX))/float64(fireCenterX) {
+	c := 1 - math.Abs(float64(x-fireCenterX))/float64(fireCenterX)
+	if r.Audio != nil {
+		// and rises and falls with the low end of the music
+		c *= fireAudioFloor + (1-fireAudioFloor)*r.intensity
+	}
+	if rand.Float64() < c {
 		r.Points[y][x] = 1
 	} else {
 		r.Points[y][x] = 0
@@ -173,6 +188,16 @@ func (g *Game) Initialize() {
 	}
 
 	g.m_Fire = NewFire()
+	if audioSource != "" {
+		feed, err := audiofeed.Open(audioSource)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"audio": audioSource,
+				"error": err,
+			}).Fatal("Failed to open audio analysis")
+		}
+		g.m_Fire.Audio = audiofeed.NewAudio(feed)
+	}
 
 	g.ChangeState(STATE_LOADING)
 }
@@ -290,6 +315,8 @@ func (g *Game) NowMs() bool {
 ╚═╝     ╚═╝╚═╝  ╚═╝╚═╝╚═╝  ╚═══╝*/
 
 func main() {
+	flag.StringVar(&audioSource, "audio", audioSource, "follow music analysed by wav_store -analysis: a file, - for stdin, or udp://host:port")
+	flag.Parse()
 	runtime.LockOSThread()
 	game := NewGame()
 	os.Exit(game.Start())
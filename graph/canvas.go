@@ -0,0 +1,293 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/charneykaye/go-SDL-experiements/plotview"
+	"github.com/veandco/go-sdl2/sdl"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+)
+
+/* the graph is drawn onto a
+ ██████╗ █████╗ ███╗   ██╗██╗   ██╗ █████╗ ███████╗
+██╔════╝██╔══██╗████╗  ██║██║   ██║██╔══██╗██╔════╝
+██║     ███████║██╔██╗ ██║██║   ██║███████║███████╗
+██║     ██╔══██║██║╚██╗██║╚██╗ ██╔╝██╔══██║╚════██║
+╚██████╗██║  ██║██║ ╚████║ ╚████╔╝ ██║  ██║███████║
+ ╚═════╝╚═╝  ╚═╝╚═╝  ╚═══╝  ╚═══╝  ╚═╝  ╚═╝╚══════╝*/
+
+// Canvas is somewhere the graph can be drawn: the screen, an image or an SVG.
+// Positions are in pixels; colours are 0xAARRGGBB.
+type Canvas interface {
+	// Scale is how many pixels make one screen pixel, for line widths and text
+	Scale() int32
+	FillRect(x, y, w, h int32, color uint32)
+	Polyline(points []Point, color uint32)
+	// Text draws s in the bitmap font with its top-left corner at x, y
+	Text(s string, x, y int32, color uint32)
+	// Image stretches img, pixel for pixel, over the rectangle at x, y
+	Image(img *image.RGBA, x, y, w, h int32)
+}
+
+// RasterCanvas draws onto 32-bit pixels with an alpha byte at offset 3
+type RasterCanvas struct {
+	Pix     []byte
+	Stride  int
+	W, H    int32
+	scale   int32
+	r, g, b int // byte offset of each colour in a pixel
+	/* private: coverage of the polyline being drawn, so joints aren't blended twice */
+	coverage []float32
+	covered  []int
+}
+
+// NewSurfaceCanvas draws onto a 32-bit ARGB surface, e.g. the screen surface
+func NewSurfaceCanvas(surface *sdl.Surface) *RasterCanvas {
+	return &RasterCanvas{
+		Pix:    surface.Pixels(),
+		Stride: int(surface.Pitch),
+		W:      surface.W,
+		H:      surface.H,
+		scale:  1,
+		r:      2, g: 1, b: 0,
+	}
+}
+
+func NewImageCanvas(img *image.RGBA, scale int32) *RasterCanvas {
+	return &RasterCanvas{
+		Pix:    img.Pix,
+		Stride: img.Stride,
+		W:      int32(img.Rect.Dx()),
+		H:      int32(img.Rect.Dy()),
+		scale:  scale,
+		r:      0, g: 1, b: 2,
+	}
+}
+
+func (c *RasterCanvas) Scale() int32 {
+	return c.scale
+}
+
+func (c *RasterCanvas) FillRect(x, y, w, h int32, color uint32) {
+	x0, y0 := maxInt32(x, 0), maxInt32(y, 0)
+	x1, y1 := minInt32(x+w, c.W), minInt32(y+h, c.H)
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			c.blend(px, py, color, 1)
+		}
+	}
+}
+
+// Polyline draws an anti-aliased line lineWidth screen pixels wide through the points
+func (c *RasterCanvas) Polyline(points []Point, color uint32) {
+	if c.coverage == nil {
+		c.coverage = make([]float32, int(c.W)*int(c.H))
+	}
+	for i := 1; i < len(points); i++ {
+		c.line(points[i-1], points[i])
+	}
+	for _, i := range c.covered {
+		c.blend(int32(i%int(c.W)), int32(i/int(c.W)), color, float64(c.coverage[i]))
+		c.coverage[i] = 0
+	}
+	c.covered = c.covered[:0]
+}
+
+func (c *RasterCanvas) Text(s string, x, y int32, color uint32) {
+	plotview.DrawText(s, x, y, c.scale, func(x, y, w, h int32) {
+		c.FillRect(x, y, w, h, color)
+	})
+}
+
+func (c *RasterCanvas) Image(img *image.RGBA, x, y, w, h int32) {
+	iw, ih := int32(img.Rect.Dx()), int32(img.Rect.Dy())
+	x0, y0 := maxInt32(x, 0), maxInt32(y, 0)
+	x1, y1 := minInt32(x+w, c.W), minInt32(y+h, c.H)
+	for py := y0; py < y1; py++ {
+		row := img.Pix[int((py-y)*ih/h)*img.Stride:]
+		for px := x0; px < x1; px++ {
+			src := row[int((px-x)*iw/w)*4:]
+			i := int(py)*c.Stride + int(px)*4
+			c.Pix[i+c.r], c.Pix[i+c.g], c.Pix[i+c.b], c.Pix[i+3] = src[0], src[1], src[2], 0xFF
+		}
+	}
+}
+
+// line covers parallel 1 pixel lines
+func (c *RasterCanvas) line(a, b Point) {
+	a, b, ok := clipLine(a, b, float64(c.W-1), float64(c.H-1))
+	if !ok {
+		return
+	}
+	dx, dy := b.X-a.X, b.Y-a.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		c.cover(int32(a.X), int32(a.Y), 1)
+		return
+	}
+	nx, ny := -dy/length, dx/length
+	width := lineWidth * int(c.scale)
+	for k := 0; k < width; k++ {
+		o := float64(k) - float64(width-1)/2
+		c.wu(a.X+o*nx, a.Y+o*ny, b.X+o*nx, b.Y+o*ny)
+	}
+}
+
+// wu is Xiaolin Wu's line algorithm
+func (c *RasterCanvas) wu(x0, y0, x1, y1 float64) {
+	steep := math.Abs(y1-y0) > math.Abs(x1-x0)
+	if steep {
+		x0, y0, x1, y1 = y0, x0, y1, x1
+	}
+	if x0 > x1 {
+		x0, x1, y0, y1 = x1, x0, y1, y0
+	}
+	plot := func(x, y float64, coverage float64) {
+		if steep {
+			x, y = y, x
+		}
+		c.cover(int32(x), int32(y), coverage)
+	}
+	gradient := 1.0
+	if x1 != x0 {
+		gradient = (y1 - y0) / (x1 - x0)
+	}
+	y := y0 + gradient*(math.Round(x0)-x0)
+	for x := math.Round(x0); x <= math.Round(x1); x++ {
+		fy := math.Floor(y)
+		plot(x, fy, 1-(y-fy))
+		plot(x, fy+1, y-fy)
+		y += gradient
+	}
+}
+
+// cover records the greatest coverage of the pixel at x, y by the polyline
+func (c *RasterCanvas) cover(x, y int32, coverage float64) {
+	if x < 0 || y < 0 || x >= c.W || y >= c.H || coverage <= 0 {
+		return
+	}
+	i := int(y)*int(c.W) + int(x)
+	if c.coverage[i] == 0 {
+		c.covered = append(c.covered, i)
+	}
+	c.coverage[i] = float32(math.Max(float64(c.coverage[i]), coverage))
+}
+
+// blend mixes color into the pixel at x, y by coverage
+func (c *RasterCanvas) blend(x, y int32, color uint32, coverage float64) {
+	if x < 0 || y < 0 || x >= c.W || y >= c.H || coverage <= 0 {
+		return
+	}
+	i := int(y)*c.Stride + int(x)*4
+	if i+3 >= len(c.Pix) {
+		return
+	}
+	coverage = math.Min(1, coverage)
+	for _, ch := range [][2]int{{c.r, 16}, {c.g, 8}, {c.b, 0}} {
+		src := float64(color >> uint(ch[1]) & 0xFF)
+		dst := float64(c.Pix[i+ch[0]])
+		c.Pix[i+ch[0]] = uint8(dst + (src-dst)*coverage)
+	}
+	c.Pix[i+3] = 0xFF
+}
+
+func NewSVGCanvas(w io.Writer, width, height, scale int32) *SVGCanvas {
+	c := &SVGCanvas{w: w, W: width, H: height, scale: scale}
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	return c
+}
+
+// SVGCanvas writes each shape as an SVG element; call Close to finish the document
+type SVGCanvas struct {
+	W, H  int32
+	w     io.Writer
+	scale int32
+}
+
+func (c *SVGCanvas) Scale() int32 {
+	return c.scale
+}
+
+func (c *SVGCanvas) FillRect(x, y, w, h int32, color uint32) {
+	fmt.Fprintf(c.w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", x, y, w, h, svgColor(color))
+}
+
+// Polyline is clipped to the canvas, so asymptotes don't write huge coordinates
+func (c *SVGCanvas) Polyline(points []Point, color uint32) {
+	var run []Point
+	flush := func() {
+		if len(run) < 2 {
+			run = run[:0]
+			return
+		}
+		coords := make([]string, len(run))
+		for i, p := range run {
+			coords[i] = fmt.Sprintf("%.2f,%.2f", p.X, p.Y)
+		}
+		fmt.Fprintf(c.w, "<polyline points=\"%s\" fill=\"none\" stroke=\"%s\" stroke-width=\"%d\" stroke-linejoin=\"round\" stroke-linecap=\"round\"/>\n",
+			strings.Join(coords, " "), svgColor(color), int32(lineWidth)*c.scale)
+		run = run[:0]
+	}
+	for i := 1; i < len(points); i++ {
+		a, b, ok := clipLine(points[i-1], points[i], float64(c.W), float64(c.H))
+		if !ok {
+			flush()
+			continue
+		}
+		if len(run) > 0 && run[len(run)-1] != a {
+			flush()
+		}
+		if len(run) == 0 {
+			run = append(run, a)
+		}
+		run = append(run, b)
+	}
+	flush()
+}
+
+func (c *SVGCanvas) Text(s string, x, y int32, color uint32) {
+	fmt.Fprintf(c.w, "<text x=\"%d\" y=\"%d\" font-family=\"monospace\" font-size=\"%d\" fill=\"%s\" xml:space=\"preserve\">%s</text>\n",
+		x, y+plotview.FontAscent*c.scale, plotview.FontHeight*c.scale, svgColor(color), svgEscaper.Replace(s))
+}
+
+// Image is embedded as a PNG
+func (c *SVGCanvas) Image(img *image.RGBA, x, y, w, h int32) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return
+	}
+	fmt.Fprintf(c.w, "<image x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" preserveAspectRatio=\"none\" style=\"image-rendering:pixelated\" href=\"data:image/png;base64,%s\"/>\n",
+		x, y, w, h, base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+func (c *SVGCanvas) Close() error {
+	_, err := io.WriteString(c.w, "</svg>\n")
+	return err
+}
+
+var svgEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;")
+
+func svgColor(color uint32) string {
+	return fmt.Sprintf("#%06x", color&0xFFFFFF)
+}
+
+func minInt32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
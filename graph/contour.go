@@ -0,0 +1,194 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"github.com/charneykaye/go-SDL-experiements/plotview"
+	"math"
+)
+
+/* where a surface crosses a level, it has a
+ ██████╗ ██████╗ ███╗   ██╗████████╗ ██████╗ ██╗   ██╗██████╗
+██╔════╝██╔═══██╗████╗  ██║╚══██╔══╝██╔═══██╗██║   ██║██╔══██╗
+██║     ██║   ██║██╔██╗ ██║   ██║   ██║   ██║██║   ██║██████╔╝
+██║     ██║   ██║██║╚██╗██║   ██║   ██║   ██║██║   ██║██╔══██╗
+╚██████╗╚██████╔╝██║ ╚████║   ██║   ╚██████╔╝╚██████╔╝██║  ██║
+ ╚═════╝ ╚═════╝ ╚═╝  ╚═══╝   ╚═╝    ╚═════╝  ╚═════╝ ╚═╝  ╚═╝*/
+
+// isoEdge is the edge of a grid cell, left of or above grid point I, J
+type isoEdge struct {
+	I, J     int
+	Vertical bool
+}
+
+type isoSegment struct {
+	A, B isoEdge
+}
+
+// Isolines are where f(x, y) = level across the view, found by marching
+// squares over a grid of isoCellSize pixels and joined into polylines
+func (r *Graph) Isolines(f func(x, y float64) float64, level float64) [][]Point {
+	return r.IsoGrid(f).Lines(level)
+}
+
+// IsoGrid is f sampled across the view, to find the isolines of any number of levels
+type IsoGrid struct {
+	F      func(x, y float64) float64
+	View   *plotview.Viewport
+	Cell   float64 // pixels square
+	NX, NY int
+	Values []float64
+}
+
+func (r *Graph) IsoGrid(f func(x, y float64) float64) *IsoGrid {
+	cell := float64(isoCellSize * r.canvas.Scale())
+	g := &IsoGrid{
+		F:    f,
+		View: r.View,
+		Cell: cell,
+		NX:   int(math.Ceil(float64(r.View.W)/cell)) + 1,
+		NY:   int(math.Ceil(float64(r.View.H)/cell)) + 1,
+	}
+	g.Values = make([]float64, g.NX*g.NY)
+	for j := 0; j < g.NY; j++ {
+		y := r.View.FromScreenY(float64(j) * cell)
+		for i := 0; i < g.NX; i++ {
+			g.Values[j*g.NX+i] = f(r.View.FromScreenX(float64(i)*cell), y)
+		}
+	}
+	return g
+}
+
+// Lines are where f(x, y) = level, joined into polylines
+func (g *IsoGrid) Lines(level float64) [][]Point {
+	f, cell, nx, ny := g.F, g.Cell, g.NX, g.NY
+	at := func(i, j int) float64 {
+		return g.Values[j*nx+i] - level
+	}
+
+	points := map[isoEdge]Point{}
+	// cross finds where the level crosses an edge by bisection, unless f
+	// passes through a pole there rather than through the level (e.g. tan),
+	// which shows as values growing rather than shrinking toward it
+	cross := func(e isoEdge) bool {
+		if _, ok := points[e]; ok {
+			return true
+		}
+		di, dj := 1.0, 0.0
+		if e.Vertical {
+			di, dj = 0, 1
+		}
+		point := func(frac float64) Point {
+			return Point{(float64(e.I) + frac*di) * cell, (float64(e.J) + frac*dj) * cell}
+		}
+		a, b := at(e.I, e.J), at(e.I+int(di), e.J+int(dj))
+		lo, hi, fLo, fHi := 0.0, 1.0, a, b
+		for k := 0; k < isoRefine; k++ {
+			mid := (lo + hi) / 2
+			p := point(mid)
+			fMid := f(g.View.FromScreenX(p.X), g.View.FromScreenY(p.Y)) - level
+			if !finite(fMid) {
+				return false
+			}
+			if (fMid > 0) == (fLo > 0) {
+				lo, fLo = mid, fMid
+			} else {
+				hi, fHi = mid, fMid
+			}
+		}
+		if math.Abs(fLo)+math.Abs(fHi) > math.Abs(a)+math.Abs(b) {
+			return false
+		}
+		points[e] = point(lo + (hi-lo)*fLo/(fLo-fHi))
+		return true
+	}
+
+	var segments []isoSegment
+	ends := map[isoEdge][]int{}
+	join := func(a, b isoEdge) {
+		if !cross(a) || !cross(b) {
+			return
+		}
+		ends[a] = append(ends[a], len(segments))
+		ends[b] = append(ends[b], len(segments))
+		segments = append(segments, isoSegment{a, b})
+	}
+	for j := 0; j < ny-1; j++ {
+		for i := 0; i < nx-1; i++ {
+			tl, tr, br, bl := at(i, j), at(i+1, j), at(i+1, j+1), at(i, j+1)
+			if !finite(tl) || !finite(tr) || !finite(br) || !finite(bl) {
+				continue
+			}
+			top, right, bottom, left := isoEdge{i, j, false}, isoEdge{i + 1, j, true}, isoEdge{i, j + 1, false}, isoEdge{i, j, true}
+			index := 0
+			for bit, v := range []float64{tl, tr, br, bl} {
+				if v > 0 {
+					index |= 1 << uint(bit)
+				}
+			}
+			switch index {
+			case 1, 14:
+				join(left, top)
+			case 2, 13:
+				join(top, right)
+			case 3, 12:
+				join(left, right)
+			case 4, 11:
+				join(right, bottom)
+			case 6, 9:
+				join(top, bottom)
+			case 7, 8:
+				join(left, bottom)
+			case 5, 10:
+				// a saddle: the centre decides which corners are connected
+				if (tl+tr+br+bl > 0) == (index == 5) {
+					join(left, bottom)
+					join(top, right)
+				} else {
+					join(left, top)
+					join(right, bottom)
+				}
+			}
+		}
+	}
+
+	var lines [][]Point
+	used := make([]bool, len(segments))
+	extend := func(chain []isoEdge) []isoEdge {
+		for {
+			tail, next := chain[len(chain)-1], -1
+			for _, k := range ends[tail] {
+				if !used[k] {
+					next = k
+					break
+				}
+			}
+			if next < 0 {
+				return chain
+			}
+			used[next] = true
+			if segments[next].A == tail {
+				chain = append(chain, segments[next].B)
+			} else {
+				chain = append(chain, segments[next].A)
+			}
+		}
+	}
+	for k, s := range segments {
+		if used[k] {
+			continue
+		}
+		used[k] = true
+		chain := extend([]isoEdge{s.A, s.B})
+		for a, b := 0, len(chain)-1; a < b; a, b = a+1, b-1 {
+			chain[a], chain[b] = chain[b], chain[a]
+		}
+		chain = extend(chain)
+		line := make([]Point, len(chain))
+		for i, e := range chain {
+			line[i] = points[e]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
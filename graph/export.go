@@ -0,0 +1,93 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/* plots go into reports as an
+███████╗██╗  ██╗██████╗  ██████╗ ██████╗ ████████╗
+██╔════╝╚██╗██╔╝██╔══██╗██╔═══██╗██╔══██╗╚══██╔══╝
+█████╗   ╚███╔╝ ██████╔╝██║   ██║██████╔╝   ██║
+██╔══╝   ██╔██╗ ██╔═══╝ ██║   ██║██╔══██╗   ██║
+███████╗██╔╝ ██╗██║     ╚██████╔╝██║  ██║   ██║
+╚══════╝╚═╝  ╚═╝╚═╝      ╚═════╝ ╚═╝  ╚═╝   ╚═╝*/
+
+// Export draws the graph as it is in view to a .png or .svg file of
+// width x height pixels, with text and lines scaled up to match
+func (r *Graph) Export(path string, width, height int32) (err error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".svg" && ext != ".png" {
+		return fmt.Errorf("can only export .png or .svg, not %q", path)
+	}
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("can't export %dx%d pixels", width, height)
+	}
+	scale := int32(math.Max(1, math.Round(math.Min(
+		float64(width)/float64(r.View.W),
+		float64(height)/float64(r.View.H)))))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	out := bufio.NewWriter(f)
+	defer func() {
+		if ferr := out.Flush(); err == nil {
+			err = ferr
+		}
+	}()
+
+	if ext == ".svg" {
+		canvas := NewSVGCanvas(out, width, height, scale)
+		r.DrawTo(canvas, width, height)
+		return canvas.Close()
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	r.DrawTo(NewImageCanvas(img, scale), width, height)
+	return png.Encode(out, img)
+}
+
+// DrawTo draws the same region of plot space onto another canvas and size
+func (r *Graph) DrawTo(canvas Canvas, width, height int32) {
+	screenView, screenCanvas := r.View, r.canvas
+	defer func() {
+		r.View, r.canvas = screenView, screenCanvas
+	}()
+	view := *screenView
+	view.W, view.H = width, height
+	r.View, r.canvas = &view, canvas
+	canvas.FillRect(0, 0, width, height, 0xFF000000)
+	r.Draw()
+}
+
+// export is the headless command: plot what's given on the command line to exportPath
+func export() int {
+	r := NewGraph(nil)
+	r.Load(false)
+	if err := r.Export(exportPath, exportWidth, exportHeight); err != nil {
+		log.WithFields(log.Fields{
+			"file":  exportPath,
+			"error": err,
+		}).Error("Failed to export")
+		return 1
+	}
+	log.WithFields(log.Fields{
+		"file": exportPath,
+	}).Info("Exported")
+	return 0
+}
@@ -0,0 +1,58 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	graphExpressions = []string{"sin(x)*exp(-x/5)"}
+	defer func() { graphExpressions = nil }()
+	r := NewGraph(nil)
+	r.Load(false)
+
+	path := filepath.Join(dir, "plot.png")
+	if err := r.Export(path, 320, 240); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if img, err := png.Decode(f); err != nil || img.Bounds().Dx() != 320 || img.Bounds().Dy() != 240 {
+		t.Fatalf("exported a PNG that decodes with %v", err)
+	}
+	path = filepath.Join(dir, "plot.SVG")
+	if err := r.Export(path, 320, 240); err != nil {
+		t.Fatal(err)
+	}
+	if svg, err := os.ReadFile(path); err != nil || !strings.HasPrefix(string(svg), "<svg") || !strings.HasSuffix(strings.TrimSpace(string(svg)), "</svg>") {
+		t.Fatalf("exported an SVG that reads with %v", err)
+	}
+
+	// refused before anything is written, not leaving an empty file behind
+	for _, c := range []struct {
+		name          string
+		width, height int32
+	}{
+		{"plot.jpg", 320, 240},
+		{"plot", 320, 240},
+		{"empty.png", 0, 240},
+		{"negative.svg", 320, -1},
+	} {
+		path := filepath.Join(dir, c.name)
+		if err := r.Export(path, c.width, c.height); err == nil {
+			t.Errorf("exported %s at %dx%d", c.name, c.width, c.height)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("left %s behind", c.name)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* curves are described by an
+███████╗██╗  ██╗██████╗ ██████╗
+██╔════╝╚██╗██╔╝██╔══██╗██╔══██╗
+█████╗   ╚███╔╝ ██████╔╝██████╔╝
+██╔══╝   ██╔██╗ ██╔═══╝ ██╔══██╗
+███████╗██╔╝ ██╗██║     ██║  ██║
+╚══════╝╚═╝  ╚═╝╚═╝     ╚═╝  ╚═╝*/
+
+// Vars are the values of the variables while evaluating an Expr
+type Vars map[string]float64
+
+// Expr is a parsed expression, e.g. sin(x)*exp(-x/5)
+type Expr interface {
+	Eval(v Vars) float64
+}
+
+type exprNumber float64
+
+func (e exprNumber) Eval(v Vars) float64 {
+	return float64(e)
+}
+
+type exprVariable string
+
+func (e exprVariable) Eval(v Vars) float64 {
+	return v[string(e)]
+}
+
+type exprNegate struct {
+	X Expr
+}
+
+func (e exprNegate) Eval(v Vars) float64 {
+	return -e.X.Eval(v)
+}
+
+type exprBinary struct {
+	Op   byte
+	L, R Expr
+}
+
+func (e exprBinary) Eval(v Vars) float64 {
+	l, r := e.L.Eval(v), e.R.Eval(v)
+	switch e.Op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	case '/':
+		return l / r
+	case '%':
+		return math.Mod(l, r)
+	case '^':
+		return math.Pow(l, r)
+	}
+	return math.NaN()
+}
+
+type exprCall struct {
+	F    func(args []float64) float64
+	Args []Expr
+	vals []float64
+}
+
+func (e *exprCall) Eval(v Vars) float64 {
+	for i, a := range e.Args {
+		e.vals[i] = a.Eval(v)
+	}
+	return e.F(e.vals)
+}
+
+type exprFunc struct {
+	Arity int
+	F     func(args []float64) float64
+}
+
+func exprFunc1(f func(float64) float64) exprFunc {
+	return exprFunc{1, func(a []float64) float64 { return f(a[0]) }}
+}
+
+func exprFunc2(f func(float64, float64) float64) exprFunc {
+	return exprFunc{2, func(a []float64) float64 { return f(a[0], a[1]) }}
+}
+
+var exprFuncs = map[string]exprFunc{
+	"sin":   exprFunc1(math.Sin),
+	"cos":   exprFunc1(math.Cos),
+	"tan":   exprFunc1(math.Tan),
+	"asin":  exprFunc1(math.Asin),
+	"acos":  exprFunc1(math.Acos),
+	"atan":  exprFunc1(math.Atan),
+	"sinh":  exprFunc1(math.Sinh),
+	"cosh":  exprFunc1(math.Cosh),
+	"tanh":  exprFunc1(math.Tanh),
+	"exp":   exprFunc1(math.Exp),
+	"ln":    exprFunc1(math.Log),
+	"log":   exprFunc1(math.Log10),
+	"log2":  exprFunc1(math.Log2),
+	"sqrt":  exprFunc1(math.Sqrt),
+	"cbrt":  exprFunc1(math.Cbrt),
+	"abs":   exprFunc1(math.Abs),
+	"floor": exprFunc1(math.Floor),
+	"ceil":  exprFunc1(math.Ceil),
+	"round": exprFunc1(math.Round),
+	"sign":  exprFunc1(exprSign),
+	"atan2": exprFunc2(math.Atan2),
+	"pow":   exprFunc2(math.Pow),
+	"hypot": exprFunc2(math.Hypot),
+	"mod":   exprFunc2(math.Mod),
+	"min":   exprFunc2(math.Min),
+	"max":   exprFunc2(math.Max),
+}
+
+var exprConstants = map[string]float64{
+	"pi":  math.Pi,
+	"tau": 2 * math.Pi,
+	"e":   math.E,
+	"phi": math.Phi,
+}
+
+func exprSign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return x
+}
+
+// ParseExpr parses src, in which the only variables allowed are vars
+func ParseExpr(src string, vars ...string) (Expr, error) {
+	p := &exprParser{src: src, vars: vars}
+	p.next()
+	e, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, p.errorf("unexpected %q", p.tok)
+	}
+	return e, nil
+}
+
+// exprParser is a recursive descent parser, reading one token ahead
+type exprParser struct {
+	src  string
+	vars []string
+	pos  int    // of the next token
+	tok  string // current token, empty at the end
+	at   int    // position of the current token
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("column %d: %s", utf8.RuneCountInString(p.src[:p.at])+1, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) next() {
+	for c, size := p.runeAt(p.pos); unicode.IsSpace(c); c, size = p.runeAt(p.pos) {
+		p.pos += size
+	}
+	p.at = p.pos
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+	c, size := p.runeAt(p.pos)
+	end := p.pos + size
+	switch {
+	case isDigit(c) || c == '.':
+		end = p.scan(end, func(c rune) bool { return isDigit(c) || c == '.' })
+		// exponent, e.g. 1e-3
+		if c, size := p.runeAt(end); c == 'e' || c == 'E' {
+			exp := end + size
+			if c, size := p.runeAt(exp); c == '+' || c == '-' {
+				exp += size
+			}
+			if c, _ := p.runeAt(exp); isDigit(c) {
+				end = p.scan(exp, isDigit)
+			}
+		}
+	case unicode.IsLetter(c) || c == '_':
+		end = p.scan(end, func(c rune) bool { return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' })
+	case c == '*':
+		// ** is the same as ^
+		if c, size := p.runeAt(end); c == '*' {
+			end += size
+		}
+	}
+	p.tok = p.src[p.pos:end]
+	p.pos = end
+}
+
+// runeAt is the rune of the source starting at byte i, and its size; 0 at the end
+func (p *exprParser) runeAt(i int) (rune, int) {
+	if i >= len(p.src) {
+		return 0, 0
+	}
+	return utf8.DecodeRuneInString(p.src[i:])
+}
+
+// scan is where the run of runes from byte i that are all in ends
+func (p *exprParser) scan(i int, in func(rune) bool) int {
+	for c, size := p.runeAt(i); size > 0 && in(c); c, size = p.runeAt(i) {
+		i += size
+	}
+	return i
+}
+
+// isDigit is only the digits strconv can read
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parseSum := product (('+' | '-') product)*
+func (p *exprParser) parseSum() (Expr, error) {
+	l, err := p.parseProduct()
+	for err == nil && (p.tok == "+" || p.tok == "-") {
+		op := p.tok[0]
+		p.next()
+		var r Expr
+		if r, err = p.parseProduct(); err == nil {
+			l = exprBinary{op, l, r}
+		}
+	}
+	return l, err
+}
+
+// parseProduct := unary (('*' | '/' | '%') unary | implicit)*
+// where implicit multiplication follows a number, e.g. 2x or 3(x+1)
+func (p *exprParser) parseProduct() (Expr, error) {
+	l, err := p.parseUnary()
+	for err == nil {
+		op := byte('*')
+		switch {
+		case p.tok == "*" || p.tok == "/" || p.tok == "%":
+			op = p.tok[0]
+			p.next()
+		case p.implicit(l):
+		default:
+			return l, nil
+		}
+		var r Expr
+		if r, err = p.parseUnary(); err == nil {
+			l = exprBinary{op, l, r}
+		}
+	}
+	return l, err
+}
+
+func (p *exprParser) implicit(l Expr) bool {
+	if _, ok := l.(exprNumber); !ok || p.tok == "" {
+		return false
+	}
+	c, _ := utf8.DecodeRuneInString(p.tok)
+	return c == '(' || unicode.IsLetter(c)
+}
+
+// parseUnary := ('-' | '+') unary | power
+func (p *exprParser) parseUnary() (Expr, error) {
+	switch p.tok {
+	case "-":
+		p.next()
+		x, err := p.parseUnary()
+		return exprNegate{x}, err
+	case "+":
+		p.next()
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+// parsePower := operand ('^' unary)?, so that 2^-x and -x^2 = -(x^2) work
+func (p *exprParser) parsePower() (Expr, error) {
+	l, err := p.parseOperand()
+	if err != nil || (p.tok != "^" && p.tok != "**") {
+		return l, err
+	}
+	p.next()
+	r, err := p.parseUnary()
+	return exprBinary{'^', l, r}, err
+}
+
+// parseOperand := number | constant | variable | function '(' args ')' | '(' sum ')'
+func (p *exprParser) parseOperand() (Expr, error) {
+	tok := p.tok
+	c, _ := utf8.DecodeRuneInString(tok)
+	switch {
+	case tok == "":
+		return nil, p.errorf("unexpected end")
+	case tok == "(":
+		p.next()
+		e, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, p.errorf("expected )")
+		}
+		p.next()
+		return e, nil
+	case isDigit(c) || c == '.':
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, p.errorf("bad number %q", tok)
+		}
+		p.next()
+		return exprNumber(f), nil
+	case unicode.IsLetter(c) || c == '_':
+		name := strings.ToLower(tok)
+		for _, v := range p.vars {
+			if name == v {
+				p.next()
+				return exprVariable(name), nil
+			}
+		}
+		if c, ok := exprConstants[name]; ok {
+			p.next()
+			return exprNumber(c), nil
+		}
+		if f, ok := exprFuncs[name]; ok {
+			return p.parseCall(name, f)
+		}
+		return nil, p.errorf("unknown name %q", tok)
+	}
+	return nil, p.errorf("unexpected %q", tok)
+}
+
+func (p *exprParser) parseCall(name string, f exprFunc) (Expr, error) {
+	p.next()
+	if p.tok != "(" {
+		return nil, p.errorf("expected ( after %s", name)
+	}
+	p.next()
+	var args []Expr
+	for {
+		a, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+		if p.tok != "," {
+			break
+		}
+		p.next()
+	}
+	if p.tok != ")" {
+		return nil, p.errorf("expected ) after arguments to %s", name)
+	}
+	if len(args) != f.Arity {
+		return nil, p.errorf("%s takes %d arguments, not %d", name, f.Arity, len(args))
+	}
+	p.next()
+	return &exprCall{F: f.F, Args: args, vals: make([]float64, len(args))}, nil
+}
@@ -0,0 +1,66 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		vars []string
+		want float64
+	}{
+		{"sin(x)*exp(-x/5)", []string{"x"}, math.Sin(2) * math.Exp(-0.4)},
+		{"2x^2 - 3(x+1)", []string{"x"}, -1},
+		{"x ** 2 % 3", []string{"x"}, 1},
+		{"\t1.5e-1 *\tx\n", []string{"x"}, 0.3},
+		{"2θ + 1", []string{"θ"}, 5},
+		{"max(x,\u00a0π)", []string{"x", "π"}, 3},
+	} {
+		e, err := ParseExpr(c.src, c.vars...)
+		if err != nil {
+			t.Errorf("%q: %v", c.src, err)
+			continue
+		}
+		v := Vars{}
+		for _, name := range c.vars {
+			v[name] = 2
+		}
+		if name := c.vars[len(c.vars)-1]; name == "π" {
+			v[name] = 3
+		}
+		if got := e.Eval(v); math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("%q is %v, want %v", c.src, got, c.want)
+		}
+	}
+	for _, c := range []struct {
+		src, want string
+	}{
+		{"θ + y", "column 5:"},
+		{"sin(x) €", "column 8: unexpected"},
+		{"x + €", "column 5: unexpected"},
+		{"x +", "column 4:"},
+	} {
+		if _, err := ParseExpr(c.src, "x", "θ"); err == nil || !strings.HasPrefix(err.Error(), c.want) {
+			t.Errorf("%q fails with %v, want %s ...", c.src, err, c.want)
+		}
+	}
+}
+
+func TestPromptBackspace(t *testing.T) {
+	g := &App{prompting: true, prompt: "2θ"}
+	g.KeyDown(sdl.K_BACKSPACE)
+	if g.prompt != "2" {
+		t.Errorf("backspace left %q", g.prompt)
+	}
+	g.KeyDown(sdl.K_BACKSPACE)
+	g.KeyDown(sdl.K_BACKSPACE)
+	if g.prompt != "" {
+		t.Errorf("backspace left %q", g.prompt)
+	}
+}
@@ -3,19 +3,79 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
+	"github.com/charneykaye/go-SDL-experiements/plotview"
 	"github.com/veandco/go-sdl2/sdl"
 	"math"
 	// "math/rand"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 var (
 	graphWidth, graphHeight int     = 300, 300
-	graphPointSize         int     = 2
-	graphGenRows           int     = 2
-	graphDecay             float64 = 0.98
+	graphPointSize          int     = 2
+	graphGenRows            int     = 2
+	graphDecay              float64 = 0.98
+	graphExpressions        []string
+	graphData               []string
+	graphStream             string
+	graphStyle              string = "line"
+	heatmapPalette          string = "viridis"
+	heatmapContours         int    = 10 // roughly, contours are at round numbers
+	heatmapLevels           []float64
+	heatmapCellSize         int32   = 2    // pixels square, evaluated once each
+	heatmapPercentile       float64 = 0.01 // values beyond are off the ends of the palette
+	colorBarWidth           int32   = 12
+	streamWindow            float64 = 10 // seconds of a stream in view
+	streamBufferSize        int     = 1024
+	seriesDotSize           int32   = 3
+	graphTicksX             int     = 10 // roughly, guides are spaced at round numbers
+	graphTicksY             int     = 8
+	lineSampleSpacing       float64 = 4   // pixels between samples before subdividing
+	lineFlatness            float64 = 0.5 // pixels a segment may stray from the curve
+	lineMaxDepth            int     = 8
+	lineJumpPixels          float64 = 8 // at lineMaxDepth, a bigger step is a discontinuity
+	lineWidth               int     = 2
+	traceSamples            int     = 360 // steps of t before subdividing
+	isoCellSize             int32   = 4   // pixels square, to find implicit curves
+	isoRefine               int     = 6   // bisections to place a crossing within a cell
+	graphTMin               float64 = 0
+	graphTMax               float64 = 2 * math.Pi
+	tickLabelBrightness     float64 = 0.6
+	viewDefaultXMin         float64 = -10
+	viewDefaultXMax         float64 = 10
+	viewDefaultYMin         float64 = -1
+	viewDefaultYMax         float64 = 1
+	viewZoomPerNotch        float64 = 1.2
+	viewFitPercentile       float64 = 0.02 // ignore the most extreme values when fitting
+	graphTitle              string
+	graphXLabel             string
+	graphYLabel             string
+	graphLegend             bool    = true
+	labelMargin             int32   = 6
+	labelPadding            int32   = 3
+	legendSwatch            int32   = 16
+	legendMaxChars          int     = 32
+	legendBorderBrightness  float64 = 0.35
+	titleBrightness         float64 = 0.9
+	graphCrosshair          bool    = true
+	inspectSnap             int32   = 24   // pixels from a curve to snap onto it
+	inspectStep             float64 = 0.01 // pixels either side, to measure slope
+	inspectMarker           int32   = 5
+	inspectDigits           int     = 6
+	inspectBrightness       float64 = 0.4
+	exportPath              string
+	exportWidth             int32  = 1920
+	exportHeight            int32  = 1080
+	promptMargin            int32  = 4
+	promptErrorColor        uint32 = 0xFFff5050
 )
 
 /* there is one
@@ -27,71 +87,337 @@ var (
        ▀      █    ▀     ▀
              ▀          */
 
-func NewGraph(surface *sdl.Surface) *Graph {
+func NewGraph(canvas Canvas) *Graph {
 	r := &Graph{
-		surface: surface,
+		View:       NewViewport(int32(winWidth), int32(graphPlotHeight)),
+		Title:      graphTitle,
+		XLabel:     graphXLabel,
+		YLabel:     graphYLabel,
+		ShowLegend: graphLegend,
+		canvas:     canvas,
 	}
 	r.Initialize()
 	return r
 }
 
 type Graph struct {
-	surface *sdl.Surface
+	Curves     []*Curve
+	Series     []*Series
+	View       *plotview.Viewport
+	Follow     bool // keep the latest of a stream in view
+	Field      *Field
+	Title      string
+	XLabel     string
+	YLabel     string
+	ShowLegend bool
+	/* private */
+	canvas Canvas
+	stream <-chan StreamRow
+	style  SeriesStyle
+}
+
+// Curve is one function of x plotted on the graph, or a parametric, polar
+// or implicit curve as described by its Kind
+type Curve struct {
+	Name     string
+	Kind     CurveKind
+	F        func(x float64) float64
+	Trace    func(t float64) (x, y float64) // parametric or polar
+	TMin     float64
+	TMax     float64
+	Implicit func(x, y float64) float64
+	Color    uint32
 }
 
 func (r *Graph) Initialize() {
 }
 
+// AddCurve plots f in the next colour of the curve palette
+func (r *Graph) AddCurve(name string, f func(x float64) float64) *Curve {
+	c := &Curve{
+		Name:  name,
+		F:     f,
+		Color: r.nextColor(),
+	}
+	r.Curves = append(r.Curves, c)
+	return c
+}
+
+func (r *Graph) nextColor() uint32 {
+	return curvePalette[(len(r.Curves)+len(r.Series))%len(curvePalette)]
+}
+
+// AddSeries plots each series in the next colour of the curve palette
+func (r *Graph) AddSeries(list ...*Series) {
+	for _, s := range list {
+		s.Color = r.nextColor()
+		r.Series = append(r.Series, s)
+	}
+}
+
+// Listen plots each column of the stream as a series scrolling with time
+func (r *Graph) Listen(stream <-chan StreamRow, style SeriesStyle) {
+	r.stream = stream
+	r.style = style
+	r.Follow = true
+}
+
+// ReceiveStream drains the stream without blocking
+func (r *Graph) ReceiveStream() {
+	for received := r.stream != nil; received; {
+		select {
+		case row := <-r.stream:
+			for len(r.streamSeries()) < len(row.Values) {
+				r.AddSeries(&Series{
+					Name:   "stream " + strconv.Itoa(len(r.streamSeries())+1),
+					Style:  r.style,
+					Window: 2 * streamWindow,
+				})
+			}
+			for i, s := range r.streamSeries()[:len(row.Values)] {
+				s.Add(row.T, row.Values[i])
+			}
+		default:
+			received = false
+		}
+	}
+}
+
+func (r *Graph) streamSeries() (list []*Series) {
+	for _, s := range r.Series {
+		if s.Window > 0 {
+			list = append(list, s)
+		}
+	}
+	return
+}
+
+// AddExpression parses src as a function of x, and plots it
+func (r *Graph) AddExpression(src string) (*Curve, error) {
+	e, err := ParseExpr(src, "x")
+	if err != nil {
+		return nil, err
+	}
+	vars := Vars{}
+	return r.AddCurve(src, func(x float64) float64 {
+		vars["x"] = x
+		return e.Eval(vars)
+	}), nil
+}
+
+// RemoveCurve stops plotting the most recently added curve, or else the heatmap
+func (r *Graph) RemoveCurve() {
+	if len(r.Curves) > 0 {
+		r.Curves = r.Curves[:len(r.Curves)-1]
+	} else {
+		r.Field = nil
+	}
+}
+
 func (r *Graph) Render() {
-	for i := float64(-9); i < -1; i++ {
-		r.RenderGuideV(i, 0.15)
+	r.ReceiveStream()
+	if r.Follow {
+		r.FollowStream()
+	}
+	r.Draw()
+}
+
+// Draw plots the graph as it is now onto the canvas
+func (r *Graph) Draw() {
+	stepX, ticksX := plotview.Ticks(r.View.XMin, r.View.XMax, graphTicksX)
+	stepY, ticksY := plotview.Ticks(r.View.YMin, r.View.YMax, graphTicksY)
+	if r.Field != nil {
+		r.RenderHeatmap()
+	}
+	for _, i := range ticksX {
+		r.RenderGuideV(i, plotview.GuideBrightness(i, stepX))
 	}
-	for i := float64(9); i > 1; i-- {
-		r.RenderGuideV(i, 0.15)
+	for _, o := range ticksY {
+		r.RenderGuideH(o, plotview.GuideBrightness(o, stepY))
 	}
-	r.RenderGuideV(-1, 0.25)
-	r.RenderGuideV(1, 0.25)
-	r.RenderGuideV(0, 0.35)
-	r.RenderGuideH(-0.618, 0.25)
-	r.RenderGuideH(0.618, 0.25)
-	r.RenderGuideH(0, 0.5)
-	for i := float64(-10); i <= 10; i += 0.03 {
-		r.RenderAlgorithm(i, 1)
+	if r.Field != nil && r.Field.ShowContours {
+		r.RenderContours()
+	}
+	for _, c := range r.Curves {
+		r.RenderCurve(c)
+	}
+	for _, s := range r.Series {
+		r.RenderSeries(s)
+	}
+	r.RenderTickLabels(stepX, ticksX, stepY, ticksY)
+	r.RenderTitles()
+	if r.ShowLegend {
+		r.RenderLegend()
+	}
+	if r.Field != nil {
+		r.RenderColorBar()
 	}
 }
 
-func (r *Graph) RenderAlgorithm(i float64, brightness float64) {
-	x := r.CoordI(i)
-	y := r.CoordO(r.Algorithm(i))
-	sBox := sdl.Rect{x, y, int32(graphPointSize), int32(graphPointSize)}
-	r.surface.FillRect(&sBox, 0xFFFFFFFF)
+// RenderTickLabels labels the guides along the axes, or along the edges when an axis is out of view
+func (r *Graph) RenderTickLabels(stepX float64, ticksX []float64, stepY float64, ticksY []float64) {
+	color := colorBrightness(tickLabelBrightness)
+	scale := r.canvas.Scale()
+	y := int32(math.Max(0, math.Min(float64(r.View.H-plotview.FontHeight*scale), r.View.ToScreenY(0)+float64(2*scale))))
+	for _, i := range ticksX {
+		if math.Abs(i) < stepX*1e-9 {
+			continue
+		}
+		r.canvas.Text(plotview.TickLabel(i, stepX), r.CoordI(i)+3*scale, y, color)
+	}
+	for _, o := range ticksY {
+		label := plotview.TickLabel(o, stepY)
+		x := int32(math.Max(0, math.Min(float64(r.View.W-plotview.TextWidth(label, scale)), r.View.ToScreenX(0)+float64(3*scale))))
+		r.canvas.Text(label, x, r.CoordO(o)-plotview.FontHeight*scale, color)
+	}
+}
+
+// Load plots the expressions, data and stream given on the command line,
+// or else the original Algorithm; a stream is only opened for a live graph
+func (r *Graph) Load(live bool) {
+	if _, ok := heatmapPalettes[heatmapPalette]; !ok {
+		log.WithFields(log.Fields{
+			"palette": heatmapPalette,
+		}).Fatal("Unknown heatmap palette")
+	}
+	for _, src := range graphExpressions {
+		if _, err := r.AddPlot(src); err != nil {
+			log.WithFields(log.Fields{
+				"expression": src,
+				"error":      err,
+			}).Fatal("Failed to parse expression")
+		}
+	}
+	style, ok := seriesStyleNamed(graphStyle)
+	if !ok {
+		log.WithFields(log.Fields{
+			"style": graphStyle,
+		}).Fatal("Unknown series style")
+	}
+	for _, path := range graphData {
+		list, err := LoadSeries(path, style)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"file":  path,
+				"error": err,
+			}).Fatal("Failed to load series")
+		}
+		r.AddSeries(list...)
+	}
+	if live && graphStream != "" {
+		stream, err := OpenStream(graphStream)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"stream": graphStream,
+				"error":  err,
+			}).Fatal("Failed to open stream")
+		}
+		r.Listen(stream, style)
+	}
+	if len(r.Curves) == 0 && len(r.Series) == 0 && r.stream == nil && r.Field == nil {
+		r.AddCurve("algorithm", r.Algorithm)
+	} else {
+		r.FitView()
+	}
+}
+
+// FitView fits the view to the plotted series and parametric or polar curves,
+// and to the functions over that domain or else the domain in view
+func (r *Graph) FitView() {
+	var values []float64
+	xMin, xMax, fitX := math.Inf(1), math.Inf(-1), false
+	sampled := false
+	for _, s := range r.Series {
+		if sxMin, sxMax, syMin, syMax, ok := s.Bounds(); ok {
+			xMin, xMax, fitX = math.Min(xMin, sxMin), math.Max(xMax, sxMax), true
+			values = append(values, syMin, syMax)
+		}
+	}
+	for _, c := range r.Curves {
+		if cxMin, cxMax, cyMin, cyMax, ok := c.Bounds(); ok {
+			xMin, xMax, fitX = math.Min(xMin, cxMin), math.Max(xMax, cxMax), true
+			values = append(values, cyMin, cyMax)
+		}
+	}
+	if fitX {
+		r.View.FitX(xMin, xMax)
+	}
+	step := (r.View.XMax - r.View.XMin) / float64(r.View.W)
+	for _, c := range r.Curves {
+		if c.F == nil {
+			continue
+		}
+		sampled = true
+		for i := r.View.XMin; i <= r.View.XMax; i += step {
+			if o := c.F(i); !math.IsNaN(o) && !math.IsInf(o, 0) {
+				values = append(values, o)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+	if !sampled {
+		r.View.FitY(robustRange(values, 0, 1))
+		return
+	}
+	r.View.FitY(robustRange(values, viewFitPercentile, 1-viewFitPercentile))
+}
+
+// FollowStream scrolls to the latest streamWindow seconds, fitting the values in view
+func (r *Graph) FollowStream() {
+	latest := math.Inf(-1)
+	for _, s := range r.streamSeries() {
+		if len(s.X) > 0 {
+			latest = math.Max(latest, s.X[len(s.X)-1])
+		}
+	}
+	if math.IsInf(latest, -1) {
+		return
+	}
+	r.View.XMin, r.View.XMax = latest-streamWindow, latest
+	yMin, yMax := math.Inf(1), math.Inf(-1)
+	for _, s := range r.streamSeries() {
+		for i, x := range s.X {
+			if x >= r.View.XMin && finite(s.Y[i]) {
+				yMin, yMax = math.Min(yMin, s.Y[i]), math.Max(yMax, s.Y[i])
+			}
+		}
+	}
+	if yMin <= yMax {
+		r.View.FitY(yMin, yMax)
+	}
+}
+
+func (r *Graph) RenderCurve(c *Curve) {
+	for _, line := range r.CurvePolylines(c) {
+		r.canvas.Polyline(line, c.Color)
+	}
 }
 
 func (r *Graph) RenderGuideH(i float64, brightness float64) {
-	y := r.CoordO(i)
-	sBox := sdl.Rect{0, y, int32(graphWidth * graphPointSize), int32(graphPointSize)}
-	r.surface.FillRect(&sBox, colorBrightness(brightness))
+	r.canvas.FillRect(0, r.CoordO(i), r.View.W, r.canvas.Scale(), colorBrightness(brightness))
 }
 
 func (r *Graph) RenderGuideV(i float64, brightness float64) {
-	x := r.CoordI(i)
-	sBox := sdl.Rect{x, 0, int32(graphPointSize), int32(graphHeight * graphPointSize)}
-	r.surface.FillRect(&sBox, colorBrightness(brightness))
+	r.canvas.FillRect(r.CoordI(i), 0, r.canvas.Scale(), r.View.H, colorBrightness(brightness))
 }
 
+// CoordI is the screen x of input i
 func (r *Graph) CoordI(i float64) int32 {
-	return int32(float64(graphWidth * graphPointSize) * (i / 20 + 0.5))
+	return int32(math.Floor(r.View.ToScreenX(i)))
 }
 
-func (r *Graph) CoordO(i float64) int32 {
-	return int32(float64(graphWidth * graphPointSize) * (i / 2 + 0.5))
+// CoordO is the screen y of output o
+func (r *Graph) CoordO(o float64) int32 {
+	return int32(math.Floor(r.View.ToScreenY(o)))
 }
 
 func (r *Graph) Algorithm(i float64) float64 {
 	if i < -1 {
-		return -math.Log(-i - 0.85) / 14 - 0.75
+		return -math.Log(-i-0.85)/14 - 0.75
 	} else if i > 1 {
-		return math.Log(i - 0.85) / 14 + 0.75
+		return math.Log(i-0.85)/14 + 0.75
 	} else {
 		return i / 1.61803398875
 	}
@@ -111,7 +437,8 @@ is within the
 
 func NewApp() *App {
 	g := &App{
-		Name: "graph",
+		Name:       "graph",
+		inspecting: graphCrosshair,
 	}
 	g.Initialize()
 	return g
@@ -121,11 +448,22 @@ type App struct {
 	/* public */
 	Name string
 	/* private objects */
-	graph *Graph
+	graph  *Graph
+	canvas *RasterCanvas
+	/* private: mouse */
+	dragging   bool
+	inspecting bool
+	hovering   bool
+	mouseX     int32
+	mouseY     int32
+	/* private: prompt for a new expression */
+	prompting   bool
+	prompt      string
+	promptError string
 	/* private */
-	state StateEnum
-	nowMs   uint32
-	lastMs  uint32
+	state  StateEnum
+	nowMs  uint32
+	lastMs uint32
 	/* private: SDL */
 	sdlRenderer      *sdl.Renderer
 	sdlScreenSurface *sdl.Surface
@@ -181,7 +519,9 @@ func (g *App) Initialize() {
 		}).Fatal("Failed to create screen surface")
 	}
 
-	g.graph = NewGraph(g.sdlScreenSurface)
+	g.canvas = NewSurfaceCanvas(g.sdlScreenSurface)
+	g.graph = NewGraph(g.canvas)
+	g.graph.Load(true)
 
 	g.ChangeState(STATE_LOADING)
 }
@@ -212,6 +552,8 @@ func (g *App) Render() {
 	g.sdlScreenSurface.FillRect(nil, 0xFF000000)
 
 	g.graph.Render()
+	g.RenderInspector()
+	g.RenderPrompt()
 
 	g.sdlScreenTexture, err = g.sdlRenderer.CreateTextureFromSurface(g.sdlScreenSurface)
 	if err != nil {
@@ -226,6 +568,60 @@ func (g *App) Render() {
 	g.sdlRenderer.Present()
 }
 
+// RenderPrompt shows the expression being typed, or why it couldn't be parsed
+func (g *App) RenderPrompt() {
+	if !g.prompting {
+		return
+	}
+	y := int32(graphPlotHeight) - plotview.FontHeight - promptMargin
+	g.canvas.FillRect(0, y-promptMargin, int32(winWidth), plotview.FontHeight+2*promptMargin, 0xFF000000)
+	g.canvas.Text("> "+g.prompt+"_", promptMargin, y, 0xFFFFFFFF)
+	if g.promptError != "" {
+		g.canvas.Text(g.promptError, promptMargin, y-plotview.FontHeight-promptMargin, promptErrorColor)
+	}
+}
+
+func (g *App) OpenPrompt() {
+	g.prompting = true
+	g.prompt = ""
+	g.promptError = ""
+	sdl.StartTextInput()
+}
+
+func (g *App) ClosePrompt() {
+	g.prompting = false
+	sdl.StopTextInput()
+}
+
+// SubmitPrompt plots the typed expression, or keeps the prompt open to fix it
+func (g *App) SubmitPrompt() {
+	if _, err := g.graph.AddPlot(g.prompt); err != nil {
+		g.promptError = err.Error()
+		return
+	}
+	log.WithFields(log.Fields{
+		"expression": g.prompt,
+	}).Info("App plotted")
+	g.ClosePrompt()
+}
+
+// Export saves the graph as it is in view as both PNG and SVG
+func (g *App) Export() {
+	name := time.Now().Format("graph-20060102-150405")
+	for _, path := range []string{name + ".png", name + ".svg"} {
+		if err := g.graph.Export(path, exportWidth, exportHeight); err != nil {
+			log.WithFields(log.Fields{
+				"file":  path,
+				"error": err,
+			}).Warn("Failed to export")
+			continue
+		}
+		log.WithFields(log.Fields{
+			"file": path,
+		}).Info("App exported")
+	}
+}
+
 func (g *App) Stop() {
 	g.ChangeState(STATE_FINISHED)
 }
@@ -270,13 +666,87 @@ func (g *App) PollEvents() {
 		case *sdl.QuitEvent:
 			g.Stop()
 		case *sdl.KeyUpEvent:
-			if t.Keysym.Sym == sdl.K_ESCAPE {
+			if t.Keysym.Sym != sdl.K_ESCAPE {
+				break
+			}
+			if g.prompting {
+				g.ClosePrompt()
+			} else {
 				g.Stop()
 			}
+		case *sdl.KeyDownEvent:
+			g.KeyDown(t.Keysym.Sym)
+		case *sdl.MouseButtonEvent:
+			if t.Button == sdl.BUTTON_LEFT {
+				g.dragging = t.State == sdl.PRESSED
+			}
+		case *sdl.MouseMotionEvent:
+			g.mouseX, g.mouseY = surfacePoint(t.X, t.Y)
+			g.hovering = true
+			if g.dragging {
+				dx, dy := surfacePoint(t.XRel, t.YRel)
+				g.graph.View.Pan(dx, dy)
+				g.graph.Follow = false
+			}
+		case *sdl.MouseWheelEvent:
+			g.graph.Follow = false
+			mx, my, _ := sdl.GetMouseState()
+			x, y := surfacePoint(int32(mx), int32(my))
+			g.graph.View.Zoom(x, y, math.Pow(viewZoomPerNotch, float64(t.Y)))
+		case *sdl.TextInputEvent:
+			if g.prompting {
+				g.prompt += textInput(t)
+			}
 		}
 	}
 }
 
+func (g *App) KeyDown(key sdl.Keycode) {
+	switch {
+	case g.prompting && key == sdl.K_RETURN:
+		g.SubmitPrompt()
+	case g.prompting && key == sdl.K_BACKSPACE:
+		// the last character, however many bytes it takes
+		_, size := utf8.DecodeLastRuneInString(g.prompt)
+		g.prompt = g.prompt[:len(g.prompt)-size]
+	case g.prompting:
+	case key == sdl.K_RETURN:
+		g.OpenPrompt()
+	case key == sdl.K_BACKSPACE:
+		g.graph.RemoveCurve()
+	case key == sdl.K_f:
+		g.graph.FitView()
+		g.graph.Follow = g.graph.stream != nil
+	case key == sdl.K_0:
+		ResetView(g.graph.View)
+		g.graph.Follow = false
+	case key == sdl.K_e:
+		g.Export()
+	case key == sdl.K_l:
+		g.graph.ShowLegend = !g.graph.ShowLegend
+	case key == sdl.K_c:
+		g.inspecting = !g.inspecting
+	case key == sdl.K_p && g.graph.Field != nil:
+		g.graph.Field.NextPalette()
+	case key == sdl.K_k && g.graph.Field != nil:
+		g.graph.Field.ShowContours = !g.graph.Field.ShowContours
+	}
+}
+
+// surfacePoint converts window pixels to screen surface pixels, which are
+// stretched to fill the window
+func surfacePoint(x, y int32) (int32, int32) {
+	return x, int32(float64(y) * float64(graphPlotHeight) / float64(winHeight))
+}
+
+func textInput(t *sdl.TextInputEvent) string {
+	n := 0
+	for n < len(t.Text) && t.Text[n] != 0 {
+		n++
+	}
+	return string(t.Text[:n])
+}
+
 func (g *App) Alive() bool {
 	return g.state < STATE_FINISHED
 }
@@ -302,11 +772,90 @@ instantiated from
        ▀          */
 
 func main() {
+	flag.Usage = func() {
+		os.Stderr.WriteString("usage: graph [flags] [expression of x]...\n")
+		flag.PrintDefaults()
+	}
+	flag.Var((*stringsFlag)(&graphData), "data", "plot the columns of a CSV or TSV file (repeatable)")
+	flag.StringVar(&graphStream, "stream", graphStream, "plot rows of numbers as they arrive: - for stdin, udp://host:port, tcp://host:port or a file")
+	flag.StringVar(&graphStyle, "style", graphStyle, "how to draw data: line, scatter or step")
+	flag.Float64Var(&streamWindow, "window", streamWindow, "seconds of the stream in view")
+	flag.StringVar(&graphTitle, "title", graphTitle, "title at the top of the plot")
+	flag.StringVar(&graphXLabel, "xlabel", graphXLabel, "title of the x axis")
+	flag.StringVar(&graphYLabel, "ylabel", graphYLabel, "title of the y axis")
+	flag.BoolVar(&graphLegend, "legend", graphLegend, "show the colour of each curve and series (toggle with L)")
+	flag.Float64Var(&graphTMin, "tmin", graphTMin, "start of t, or θ, for parametric and polar curves")
+	flag.Float64Var(&graphTMax, "tmax", graphTMax, "end of t, or θ, for parametric and polar curves")
+	flag.StringVar(&heatmapPalette, "palette", heatmapPalette, "colours of a heatmap: "+strings.Join(heatmapPaletteNames, ", ")+" (cycle with P)")
+	flag.IntVar(&heatmapContours, "contours", heatmapContours, "roughly how many contour lines to draw over a heatmap, or 0 for none (toggle with K)")
+	flag.Var((*floatsFlag)(&heatmapLevels), "levels", "draw contour lines at these values instead, e.g. -1,0,1")
+	flag.BoolVar(&graphCrosshair, "crosshair", graphCrosshair, "show the values under the mouse (toggle with C)")
+	flag.StringVar(&exportPath, "export", exportPath, "write the plot to this .png or .svg file, without opening a window")
+	flag.Var(sizeFlag{&exportWidth, &exportHeight}, "size", "width x height of exports, e.g. 1920x1080")
+	flag.Parse()
+	graphExpressions = flag.Args()
+	if exportPath != "" {
+		os.Exit(export())
+	}
 	runtime.LockOSThread()
 	app := NewApp()
 	os.Exit(app.Start())
 }
 
+// sizeFlag sets a width and height from e.g. 1920x1080
+type sizeFlag []*int32
+
+func (f sizeFlag) String() string {
+	if len(f) < 2 || f[0] == nil {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", *f[0], *f[1])
+}
+
+func (f sizeFlag) Set(v string) error {
+	var w, h int32
+	if _, err := fmt.Sscanf(v, "%dx%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+		return fmt.Errorf("size %q should be like 1920x1080", v)
+	}
+	*f[0], *f[1] = w, h
+	return nil
+}
+
+// stringsFlag collects every use of a repeatable flag
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// floatsFlag is a comma separated list of numbers
+type floatsFlag []float64
+
+func (f *floatsFlag) String() string {
+	list := make([]string, len(*f))
+	for i, v := range *f {
+		list[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return strings.Join(list, ",")
+}
+
+func (f *floatsFlag) Set(v string) error {
+	*f = nil
+	for _, s := range strings.Split(v, ",") {
+		x, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		*f = append(*f, x)
+	}
+	return nil
+}
+
 type StateEnum uint
 
 const (
@@ -318,12 +867,13 @@ const (
 )
 
 var (
-	winWidth            = graphWidth * graphPointSize
-	winHeight           = graphHeight*graphPointSize - graphPointSize*graphGenRows
+	winWidth             = graphWidth * graphPointSize
+	winHeight            = graphHeight*graphPointSize - graphPointSize*graphGenRows
 	graphLimitX          = graphWidth - 1
 	graphLimitY          = graphHeight - 1
 	graphCenterX         = graphWidth / 2
-	graphRenderOffsetSrc = &sdl.Rect{0, 0, int32(winWidth), int32(winHeight - graphPointSize*graphGenRows)}
+	graphPlotHeight      = winHeight - graphPointSize*graphGenRows
+	graphRenderOffsetSrc = &sdl.Rect{0, 0, int32(winWidth), int32(graphPlotHeight)}
 )
 
 var palette = []uint32{
@@ -345,6 +895,18 @@ var palette = []uint32{
 	0xFFe16205,
 }
 
+// each curve is drawn in the next of these colours
+var curvePalette = []uint32{
+	0xFFFFFFFF,
+	0xFFffb234,
+	0xFF4fc3f7,
+	0xFF81c784,
+	0xFFf06292,
+	0xFFba68c8,
+	0xFFfff176,
+	0xFF4db6ac,
+}
+
 func colorBrightness(b float64) uint32 {
 	return palette[int(b*float64(15))]
 }
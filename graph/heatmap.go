@@ -0,0 +1,184 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"github.com/charneykaye/go-SDL-experiements/plotview"
+	"image"
+	"math"
+	"strconv"
+)
+
+/* a function of x and y is coloured in as a
+██╗  ██╗███████╗ █████╗ ████████╗███╗   ███╗ █████╗ ██████╗
+██║  ██║██╔════╝██╔══██╗╚══██╔══╝████╗ ████║██╔══██╗██╔══██╗
+███████║█████╗  ███████║   ██║   ██╔████╔██║███████║██████╔╝
+██╔══██║██╔══╝  ██╔══██║   ██║   ██║╚██╔╝██║██╔══██║██╔═══╝
+██║  ██║███████╗██║  ██║   ██║   ██║ ╚═╝ ██║██║  ██║██║
+╚═╝  ╚═╝╚══════╝╚═╝  ╚═╝   ╚═╝   ╚═╝     ╚═╝╚═╝  ╚═╝╚═╝*/
+
+// Field is a function of x and y, drawn as a heatmap with contour lines
+type Field struct {
+	Name         string
+	F            func(x, y float64) float64
+	Palette      string
+	ShowContours bool
+	Levels       []float64 // of the contours, or else round numbers across the values in view
+	/* private: the range of values last in view, across the palette */
+	min, max float64
+}
+
+// SetField parses src as a function of x and y, and draws it as a heatmap
+func (r *Graph) SetField(name, src string) (*Field, error) {
+	e, err := ParseExpr(src, "x", "y")
+	if err != nil {
+		return nil, err
+	}
+	vars := Vars{}
+	r.Field = &Field{
+		Name: name,
+		F: func(x, y float64) float64 {
+			vars["x"], vars["y"] = x, y
+			return e.Eval(vars)
+		},
+		Palette:      heatmapPalette,
+		ShowContours: heatmapContours > 0 || len(heatmapLevels) > 0,
+		Levels:       heatmapLevels,
+	}
+	return r.Field, nil
+}
+
+// NextPalette colours the field with the next of the heatmap palettes
+func (f *Field) NextPalette() {
+	for i, name := range heatmapPaletteNames {
+		if name == f.Palette {
+			f.Palette = heatmapPaletteNames[(i+1)%len(heatmapPaletteNames)]
+			return
+		}
+	}
+	f.Palette = heatmapPaletteNames[0]
+}
+
+// RenderHeatmap evaluates the field over cells of heatmapCellSize pixels,
+// spreading the palette across the values in view
+func (r *Graph) RenderHeatmap() {
+	f := r.Field
+	cell := heatmapCellSize * r.canvas.Scale()
+	nx, ny := (r.View.W+cell-1)/cell, (r.View.H+cell-1)/cell
+	values := make([]float64, nx*ny)
+	var inView []float64
+	for j := int32(0); j < ny; j++ {
+		y := r.View.FromScreenY((float64(j) + 0.5) * float64(cell))
+		for i := int32(0); i < nx; i++ {
+			v := f.F(r.View.FromScreenX((float64(i)+0.5)*float64(cell)), y)
+			values[j*nx+i] = v
+			if finite(v) {
+				inView = append(inView, v)
+			}
+		}
+	}
+	if len(inView) == 0 {
+		return
+	}
+	f.min, f.max = robustRange(inView, heatmapPercentile, 1-heatmapPercentile)
+	img := image.NewRGBA(image.Rect(0, 0, int(nx), int(ny)))
+	for k, v := range values {
+		color := uint32(0xFF000000)
+		if finite(v) {
+			color = f.Color(v)
+		}
+		img.Pix[k*4], img.Pix[k*4+1], img.Pix[k*4+2], img.Pix[k*4+3] = uint8(color>>16), uint8(color>>8), uint8(color), 0xFF
+	}
+	r.canvas.Image(img, 0, 0, nx*cell, ny*cell)
+}
+
+// RenderContours draws a line along each level of the field, in black or
+// white, whichever stands out from the heatmap there
+func (r *Graph) RenderContours() {
+	f := r.Field
+	levels := f.Levels
+	if len(levels) == 0 {
+		if f.max <= f.min {
+			return
+		}
+		_, levels = plotview.Ticks(f.min, f.max, heatmapContours)
+	}
+	grid := r.IsoGrid(f.F)
+	for _, level := range levels {
+		color := uint32(0xFFFFFFFF)
+		if luminance(f.Color(level)) > 0.5 {
+			color = 0xFF000000
+		}
+		for _, line := range grid.Lines(level) {
+			r.canvas.Polyline(line, color)
+		}
+	}
+}
+
+// RenderColorBar shows the palette from the least to the greatest value in
+// view, down the right hand side
+func (r *Graph) RenderColorBar() {
+	f := r.Field
+	if f.max <= f.min {
+		return
+	}
+	scale := r.canvas.Scale()
+	stops := heatmapPalettes[f.Palette]
+	img := image.NewRGBA(image.Rect(0, 0, 1, 256))
+	for k := 0; k < 256; k++ {
+		color := paletteColor(stops, 1-float64(k)/255)
+		img.Pix[k*4], img.Pix[k*4+1], img.Pix[k*4+2], img.Pix[k*4+3] = uint8(color>>16), uint8(color>>8), uint8(color), 0xFF
+	}
+	w, h := colorBarWidth*scale, r.View.H/3
+	x, y := r.View.W-w-labelMargin*scale, (r.View.H-h)/2
+	r.canvas.FillRect(x-scale, y-scale, w+2*scale, h+2*scale, colorBrightness(legendBorderBrightness))
+	r.canvas.Image(img, x, y, w, h)
+	color := colorBrightness(titleBrightness)
+	max, min := heatmapValue(f.max), heatmapValue(f.min)
+	r.RenderLabel(max, x-plotview.TextWidth(max, scale)-labelMargin*scale, y, color)
+	r.RenderLabel(min, x-plotview.TextWidth(min, scale)-labelMargin*scale, y+h-plotview.FontHeight*scale, color)
+}
+
+// Color of the value v, on the palette spread from the least to the greatest value in view
+func (f *Field) Color(v float64) uint32 {
+	if f.max <= f.min {
+		return paletteColor(heatmapPalettes[f.Palette], 0.5)
+	}
+	return paletteColor(heatmapPalettes[f.Palette], (v-f.min)/(f.max-f.min))
+}
+
+// paletteColor blends between the stops of a palette, for t from 0 to 1
+func paletteColor(stops []uint32, t float64) uint32 {
+	t = math.Max(0, math.Min(1, t)) * float64(len(stops)-1)
+	k := int(t)
+	if k >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	frac := t - float64(k)
+	color := uint32(0xFF000000)
+	for _, shift := range []uint{16, 8, 0} {
+		a, b := float64(stops[k]>>shift&0xFF), float64(stops[k+1]>>shift&0xFF)
+		color |= uint32(a+(b-a)*frac+0.5) << shift
+	}
+	return color
+}
+
+// luminance of a colour, from 0 to 1
+func luminance(color uint32) float64 {
+	return (0.2126*float64(color>>16&0xFF) + 0.7152*float64(color>>8&0xFF) + 0.0722*float64(color&0xFF)) / 255
+}
+
+func heatmapValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// heatmap palettes, from the least value to the greatest
+var heatmapPalettes = map[string][]uint32{
+	"viridis":  {0xFF440154, 0xFF3b528b, 0xFF21918c, 0xFF5ec962, 0xFFfde725},
+	"fire":     palette[:15],
+	"coolwarm": {0xFF3b4cc0, 0xFF8db0fe, 0xFFdddddd, 0xFFf49a7b, 0xFFb40426},
+	"gray":     {0xFF000000, 0xFFFFFFFF},
+}
+
+// the order in which P cycles through the palettes
+var heatmapPaletteNames = []string{"viridis", "fire", "coolwarm", "gray"}
@@ -0,0 +1,109 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"fmt"
+	"github.com/charneykaye/go-SDL-experiements/plotview"
+	"math"
+	"strconv"
+)
+
+/* read the numbers right off the curve with the
+██╗███╗   ██╗███████╗██████╗ ███████╗ ██████╗████████╗ ██████╗ ██████╗
+██║████╗  ██║██╔════╝██╔══██╗██╔════╝██╔════╝╚══██╔══╝██╔═══██╗██╔══██╗
+██║██╔██╗ ██║███████╗██████╔╝█████╗  ██║        ██║   ██║   ██║██████╔╝
+██║██║╚██╗██║╚════██║██╔═══╝ ██╔══╝  ██║        ██║   ██║   ██║██╔══██╗
+██║██║ ╚████║███████║██║     ███████╗╚██████╗   ██║   ╚██████╔╝██║  ██║
+╚═╝╚═╝  ╚═══╝╚══════╝╚═╝     ╚══════╝ ╚═════╝   ╚═╝    ╚═════╝ ╚═╝  ╚═╝*/
+
+// Inspection is what lies under the mouse: a point on the nearest curve, or
+// just the plot coordinates if no curve is close enough
+type Inspection struct {
+	Curve *Curve
+	X, Y  float64
+	Slope float64
+}
+
+// Inspect maps the screen pixel back to plot space and snaps to the curve
+// nearest to it, measured across the curve rather than straight up or down
+func (r *Graph) Inspect(px, py int32) (in Inspection) {
+	in.X = r.View.FromScreenX(float64(px))
+	in.Y = r.View.FromScreenY(float64(py))
+	best := float64(inspectSnap)
+	perPixelX := (r.View.XMax - r.View.XMin) / float64(r.View.W)
+	perPixelY := (r.View.YMax - r.View.YMin) / float64(r.View.H)
+	for _, c := range r.Curves {
+		if c.F == nil {
+			continue
+		}
+		y := c.F(in.X)
+		if !finite(y) {
+			continue
+		}
+		slope := derivative(c.F, in.X, perPixelX)
+		d := math.Abs(r.View.ToScreenY(y) - float64(py))
+		if finite(slope) {
+			d /= math.Hypot(1, slope*perPixelX/perPixelY)
+		}
+		if d <= best {
+			best = d
+			in.Curve, in.Y, in.Slope = c, y, slope
+		}
+	}
+	return
+}
+
+// derivative by central difference, a fraction of a pixel either side of x
+func derivative(f func(x float64) float64, x, perPixel float64) float64 {
+	h := perPixel * inspectStep
+	return (f(x+h) - f(x-h)) / (2 * h)
+}
+
+// RenderInspector draws a crosshair at the mouse, or on the curve it snaps to,
+// with a readout of the values there
+func (g *App) RenderInspector() {
+	if !g.inspecting || !g.hovering || g.dragging || g.prompting {
+		return
+	}
+	in := g.graph.Inspect(g.mouseX, g.mouseY)
+	x, y := g.mouseX, g.mouseY
+	color := colorBrightness(inspectBrightness)
+	lines := []string{"x = " + inspectValue(in.X)}
+	if in.Curve != nil {
+		y = int32(math.Floor(g.graph.View.ToScreenY(in.Y) + 0.5))
+		color = in.Curve.Color
+		lines = append(lines,
+			fmt.Sprintf("%s = %s", in.Curve.Name, inspectValue(in.Y)),
+			"slope = "+inspectValue(in.Slope))
+	} else {
+		lines = append(lines, "y = "+inspectValue(in.Y))
+	}
+	g.canvas.FillRect(x, 0, 1, g.graph.View.H, colorBrightness(inspectBrightness))
+	g.canvas.FillRect(0, y, g.graph.View.W, 1, colorBrightness(inspectBrightness))
+	g.canvas.FillRect(x-inspectMarker/2, y-inspectMarker/2, inspectMarker, inspectMarker, color)
+
+	var w int32
+	for _, s := range lines {
+		w = maxInt32(w, plotview.TextWidth(s, 1))
+	}
+	h := int32(len(lines)) * (plotview.FontHeight + labelPadding)
+	bx, by := x+inspectMarker, y+inspectMarker
+	if bx+w+2*labelPadding > g.graph.View.W {
+		bx = x - inspectMarker - w - 2*labelPadding
+	}
+	if by+h+labelPadding > g.graph.View.H {
+		by = y - inspectMarker - h - labelPadding
+	}
+	g.canvas.FillRect(bx, by, w+2*labelPadding, h+labelPadding, 0xFF000000)
+	for i, s := range lines {
+		g.canvas.Text(s, bx+labelPadding, by+labelPadding+int32(i)*(plotview.FontHeight+labelPadding), color)
+	}
+}
+
+func inspectValue(v float64) string {
+	if !finite(v) {
+		return "undefined"
+	}
+	return strconv.FormatFloat(v, 'g', inspectDigits, 64)
+}
@@ -0,0 +1,91 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"github.com/charneykaye/go-SDL-experiements/plotview"
+)
+
+/* a screenshot explains itself with
+██╗      █████╗ ██████╗ ███████╗██╗     ███████╗
+██║     ██╔══██╗██╔══██╗██╔════╝██║     ██╔════╝
+██║     ███████║██████╔╝█████╗  ██║     ███████╗
+██║     ██╔══██║██╔══██╗██╔══╝  ██║     ╚════██║
+███████╗██║  ██║██████╔╝███████╗███████╗███████║
+╚══════╝╚═╝  ╚═╝╚═════╝ ╚══════╝╚══════╝╚══════╝*/
+
+// RenderTitles puts the title at the top centre, the x axis title at the
+// bottom right and the y axis title at the top left
+func (r *Graph) RenderTitles() {
+	scale := r.canvas.Scale()
+	margin := labelMargin * scale
+	color := colorBrightness(titleBrightness)
+	top := margin
+	if r.Title != "" {
+		r.RenderLabel(r.Title, (r.View.W-plotview.TextWidth(r.Title, scale))/2, top, color)
+		top += (plotview.FontHeight + labelMargin) * scale
+	}
+	if r.YLabel != "" {
+		r.RenderLabel(r.YLabel, margin, top, color)
+	}
+	if r.XLabel != "" {
+		r.RenderLabel(r.XLabel, r.View.W-plotview.TextWidth(r.XLabel, scale)-margin, r.View.H-(plotview.FontHeight+labelMargin)*scale, color)
+	}
+}
+
+// RenderLabel draws text over a dark box, so it reads over guides and curves
+func (r *Graph) RenderLabel(text string, x, y int32, color uint32) {
+	pad := labelPadding * r.canvas.Scale()
+	r.canvas.FillRect(x-pad, y-pad, plotview.TextWidth(text, r.canvas.Scale())+2*pad, plotview.FontHeight*r.canvas.Scale()+2*pad, 0xFF000000)
+	r.canvas.Text(text, x, y, color)
+}
+
+type legendEntry struct {
+	Name    string
+	Color   uint32
+	Scatter bool
+}
+
+// RenderLegend lists the colour of each curve and series in the top right corner
+func (r *Graph) RenderLegend() {
+	var entries []legendEntry
+	for _, c := range r.Curves {
+		entries = append(entries, legendEntry{c.Name, c.Color, false})
+	}
+	for _, s := range r.Series {
+		entries = append(entries, legendEntry{s.Name, s.Color, s.Style == STYLE_SCATTER})
+	}
+	if r.Field != nil {
+		entries = append(entries, legendEntry{r.Field.Name, paletteColor(heatmapPalettes[r.Field.Palette], 0.75), true})
+	}
+	if len(entries) == 0 {
+		return
+	}
+	scale := r.canvas.Scale()
+	pad, swatch, row := labelPadding*scale, legendSwatch*scale, (plotview.FontHeight+labelPadding)*scale
+	var widest int32
+	for i := range entries {
+		// by characters, so as not to cut one in half
+		if name := []rune(entries[i].Name); len(name) > legendMaxChars {
+			entries[i].Name = string(name[:legendMaxChars-3]) + "..."
+		}
+		widest = maxInt32(widest, plotview.TextWidth(entries[i].Name, scale))
+	}
+	w := pad + swatch + pad + widest + pad
+	h := pad + row*int32(len(entries))
+	x := r.View.W - w - labelMargin*scale
+	y := labelMargin * scale
+	r.canvas.FillRect(x, y, w, h, colorBrightness(legendBorderBrightness))
+	r.canvas.FillRect(x+scale, y+scale, w-2*scale, h-2*scale, 0xFF000000)
+	for i, e := range entries {
+		ey := y + pad + row*int32(i)
+		mid := ey + plotview.FontHeight*scale/2
+		if e.Scatter {
+			size := seriesDotSize * scale
+			r.canvas.FillRect(x+pad+(swatch-size)/2, mid-size/2, size, size, e.Color)
+		} else {
+			r.canvas.FillRect(x+pad, mid-scale, swatch, int32(lineWidth)*scale, e.Color)
+		}
+		r.canvas.Text(e.Name, x+pad+swatch+pad, ey, colorBrightness(titleBrightness))
+	}
+}
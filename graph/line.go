@@ -0,0 +1,102 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+)
+
+/* curves are drawn as a
+██╗     ██╗███╗   ██╗███████╗
+██║     ██║████╗  ██║██╔════╝
+██║     ██║██╔██╗ ██║█████╗
+██║     ██║██║╚██╗██║██╔══╝
+███████╗██║██║ ╚████║███████╗
+╚══════╝╚═╝╚═╝  ╚═══╝╚══════╝*/
+
+// Point is in screen pixels
+type Point struct {
+	X, Y float64
+}
+
+// Polylines samples f across the view, more finely where it bends, and
+// breaks the line wherever f is undefined or jumps (e.g. at an asymptote)
+func (r *Graph) Polylines(f func(x float64) float64) (lines [][]Point) {
+	var line []Point
+	flush := func() {
+		if len(line) > 1 {
+			lines = append(lines, line)
+		}
+		line = nil
+	}
+	n := int(float64(r.View.W)/lineSampleSpacing) + 1
+	dx := (r.View.XMax - r.View.XMin) / float64(n)
+	a := r.sample(f, r.View.XMin)
+	for k := 1; k <= n; k++ {
+		b := r.sample(f, r.View.XMin+float64(k)*dx)
+		r.subdivide(f, a, b, 0, &line, flush)
+		a = b
+	}
+	flush()
+	return
+}
+
+// sample is the screen point of f at x, with a NaN y where f is undefined
+func (r *Graph) sample(f func(x float64) float64, x float64) Point {
+	o := f(x)
+	if math.IsInf(o, 0) {
+		o = math.NaN()
+	}
+	return Point{r.View.ToScreenX(x), r.View.ToScreenY(o)}
+}
+
+// subdivide appends the segment a-b to the line, halving it until it is
+// flat to within lineFlatness pixels or lineMaxDepth is reached
+func (r *Graph) subdivide(f func(x float64) float64, a, b Point, depth int, line *[]Point, flush func()) {
+	aOK, bOK := !math.IsNaN(a.Y), !math.IsNaN(b.Y)
+	if !aOK && !bOK {
+		flush()
+		return
+	}
+	if depth < lineMaxDepth {
+		m := r.sample(f, r.View.FromScreenX((a.X+b.X)/2))
+		if !aOK || !bOK || math.IsNaN(m.Y) || math.Abs(m.Y-(a.Y+b.Y)/2) > lineFlatness {
+			r.subdivide(f, a, m, depth+1, line, flush)
+			r.subdivide(f, m, b, depth+1, line, flush)
+			return
+		}
+	} else if !aOK || !bOK || math.Abs(b.Y-a.Y) > lineJumpPixels {
+		// a continuous curve can't still be this steep after halving so often
+		flush()
+		return
+	}
+	if len(*line) == 0 {
+		*line = append(*line, a)
+	}
+	*line = append(*line, b)
+}
+
+// clipLine is Liang-Barsky clipping of a-b to the rectangle from 0, 0 to w, h
+func clipLine(a, b Point, w, h float64) (Point, Point, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := b.X-a.X, b.Y-a.Y
+	for _, edge := range [][2]float64{{-dx, a.X}, {dx, w - a.X}, {-dy, a.Y}, {dy, h - a.Y}} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return a, b, false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+		if t0 > t1 {
+			return a, b, false
+		}
+	}
+	return Point{a.X + t0*dx, a.Y + t0*dy}, Point{a.X + t1*dx, a.Y + t1*dy}, true
+}
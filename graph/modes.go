@@ -0,0 +1,235 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+/* not every curve is y = f(x), so a plot has a
+███╗   ███╗ ██████╗ ██████╗ ███████╗
+████╗ ████║██╔═══██╗██╔══██╗██╔════╝
+██╔████╔██║██║   ██║██║  ██║█████╗
+██║╚██╔╝██║██║   ██║██║  ██║██╔══╝
+██║ ╚═╝ ██║╚██████╔╝██████╔╝███████╗
+╚═╝     ╚═╝ ╚═════╝ ╚═════╝ ╚══════╝*/
+
+// CurveKind is how a curve is described
+type CurveKind int
+
+const (
+	CURVE_FUNCTION   CurveKind = iota // y = f(x)
+	CURVE_PARAMETRIC                  // x(t), y(t)
+	CURVE_POLAR                       // r(θ)
+	CURVE_IMPLICIT                    // f(x, y) = 0
+)
+
+// AddPlot parses src in whichever form it is written, and plots it:
+//
+//	sin(x)              y = f(x), also written y = sin(x)
+//	(cos(3t), sin(2t))  parametric, for t from graphTMin to graphTMax
+//	r = 1 + cos(θ)      polar, for θ, or theta, from graphTMin to graphTMax
+//	x^2 + y^2 = 4       implicit
+//	z = sin(x)*cos(y)   a heatmap of the Field, which isn't a Curve
+func (r *Graph) AddPlot(src string) (*Curve, error) {
+	if xSrc, ySrc, ok := parametricParts(src); ok {
+		return r.AddParametric(src, xSrc, ySrc)
+	}
+	parts := strings.Split(src, "=")
+	if len(parts) > 2 {
+		return nil, errors.New("more than one =")
+	}
+	if len(parts) == 1 {
+		return r.AddExpression(src)
+	}
+	switch strings.TrimSpace(parts[0]) {
+	case "y":
+		c, err := r.AddExpression(parts[1])
+		if err == nil {
+			c.Name = src
+		}
+		return c, err
+	case "r":
+		return r.AddPolar(src, parts[1])
+	case "z":
+		_, err := r.SetField(src, parts[1])
+		return nil, err
+	}
+	return r.AddImplicit(src, parts[0]+"-("+parts[1]+")")
+}
+
+// AddParametric plots the point (x(t), y(t)) as t sweeps from graphTMin to graphTMax
+func (r *Graph) AddParametric(name, xSrc, ySrc string) (*Curve, error) {
+	x, err := ParseExpr(xSrc, "t")
+	if err != nil {
+		return nil, err
+	}
+	y, err := ParseExpr(ySrc, "t")
+	if err != nil {
+		return nil, err
+	}
+	vars := Vars{}
+	return r.addTrace(name, CURVE_PARAMETRIC, func(t float64) (float64, float64) {
+		vars["t"] = t
+		return x.Eval(vars), y.Eval(vars)
+	}), nil
+}
+
+// AddPolar plots the radius r(θ) as θ sweeps from graphTMin to graphTMax; it
+// can be written θ, theta or t
+func (r *Graph) AddPolar(name, src string) (*Curve, error) {
+	e, err := ParseExpr(src, "θ", "theta", "t")
+	if err != nil {
+		return nil, err
+	}
+	vars := Vars{}
+	return r.addTrace(name, CURVE_POLAR, func(t float64) (float64, float64) {
+		vars["θ"], vars["theta"], vars["t"] = t, t, t
+		radius := e.Eval(vars)
+		return radius * math.Cos(t), radius * math.Sin(t)
+	}), nil
+}
+
+// AddImplicit plots the points where f(x, y) = 0
+func (r *Graph) AddImplicit(name, src string) (*Curve, error) {
+	e, err := ParseExpr(src, "x", "y")
+	if err != nil {
+		return nil, err
+	}
+	vars := Vars{}
+	c := &Curve{
+		Name: name,
+		Kind: CURVE_IMPLICIT,
+		Implicit: func(x, y float64) float64 {
+			vars["x"], vars["y"] = x, y
+			return e.Eval(vars)
+		},
+		Color: r.nextColor(),
+	}
+	r.Curves = append(r.Curves, c)
+	return c, nil
+}
+
+func (r *Graph) addTrace(name string, kind CurveKind, trace func(t float64) (float64, float64)) *Curve {
+	c := &Curve{
+		Name:  name,
+		Kind:  kind,
+		Trace: trace,
+		TMin:  graphTMin,
+		TMax:  graphTMax,
+		Color: r.nextColor(),
+	}
+	r.Curves = append(r.Curves, c)
+	return c
+}
+
+// parametricParts splits "(x(t), y(t))" into its two expressions
+func parametricParts(src string) (string, string, bool) {
+	src = strings.TrimSpace(src)
+	if !strings.HasPrefix(src, "(") || !strings.HasSuffix(src, ")") {
+		return "", "", false
+	}
+	depth, comma := 0, -1
+	for i, c := range src {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i < len(src)-1 {
+				return "", "", false // the parentheses don't enclose the whole
+			}
+		case ',':
+			if depth == 1 && comma < 0 {
+				comma = i
+			}
+		}
+	}
+	if comma < 0 {
+		return "", "", false
+	}
+	return src[1:comma], src[comma+1 : len(src)-1], true
+}
+
+// Polylines of any kind of curve, in screen pixels
+func (r *Graph) CurvePolylines(c *Curve) [][]Point {
+	switch c.Kind {
+	case CURVE_PARAMETRIC, CURVE_POLAR:
+		return r.TracePolylines(c.Trace, c.TMin, c.TMax)
+	case CURVE_IMPLICIT:
+		return r.Isolines(c.Implicit, 0)
+	}
+	return r.Polylines(c.F)
+}
+
+// TracePolylines samples the trace from tMin to tMax, more finely where it
+// bends, and breaks the line wherever it is undefined or jumps
+func (r *Graph) TracePolylines(trace func(t float64) (float64, float64), tMin, tMax float64) (lines [][]Point) {
+	var line []Point
+	flush := func() {
+		if len(line) > 1 {
+			lines = append(lines, line)
+		}
+		line = nil
+	}
+	point := func(t float64) Point {
+		x, y := trace(t)
+		if !finite(x) || !finite(y) {
+			return Point{math.NaN(), math.NaN()}
+		}
+		return Point{r.View.ToScreenX(x), r.View.ToScreenY(y)}
+	}
+	var subdivide func(ta, tb float64, a, b Point, depth int)
+	subdivide = func(ta, tb float64, a, b Point, depth int) {
+		aOK, bOK := !math.IsNaN(a.X), !math.IsNaN(b.X)
+		if !aOK && !bOK {
+			flush()
+			return
+		}
+		if depth < lineMaxDepth {
+			tm := (ta + tb) / 2
+			m := point(tm)
+			if !aOK || !bOK || math.IsNaN(m.X) || math.Hypot(m.X-(a.X+b.X)/2, m.Y-(a.Y+b.Y)/2) > lineFlatness {
+				subdivide(ta, tm, a, m, depth+1)
+				subdivide(tm, tb, m, b, depth+1)
+				return
+			}
+		} else if !aOK || !bOK || math.Hypot(b.X-a.X, b.Y-a.Y) > lineJumpPixels {
+			flush()
+			return
+		}
+		if len(line) == 0 {
+			line = append(line, a)
+		}
+		line = append(line, b)
+	}
+	dt := (tMax - tMin) / float64(traceSamples)
+	a := point(tMin)
+	for k := 1; k <= traceSamples; k++ {
+		t := tMin + float64(k)*dt
+		b := point(t)
+		subdivide(t-dt, t, a, b, 0)
+		a = b
+	}
+	flush()
+	return
+}
+
+// Bounds of a parametric or polar curve, over its whole range of t
+func (c *Curve) Bounds() (xMin, xMax, yMin, yMax float64, ok bool) {
+	if c.Trace == nil {
+		return
+	}
+	xMin, xMax, yMin, yMax = math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for k := 0; k <= traceSamples; k++ {
+		x, y := c.Trace(c.TMin + (c.TMax-c.TMin)*float64(k)/float64(traceSamples))
+		if finite(x) && finite(y) {
+			xMin, xMax = math.Min(xMin, x), math.Max(xMax, x)
+			yMin, yMax = math.Min(yMin, y), math.Max(yMax, y)
+			ok = true
+		}
+	}
+	return
+}
@@ -0,0 +1,25 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAddPolar(t *testing.T) {
+	r := NewGraph(nil)
+	for _, src := range []string{"r = 1 + θ/2", "r = 1 + theta/2", "r = 1 + t/2"} {
+		c, err := r.AddPlot(src)
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		// named as written, not as parsed
+		if c.Name != src || c.Kind != CURVE_POLAR {
+			t.Errorf("%q plotted as %q, of kind %d", src, c.Name, c.Kind)
+		}
+		if x, y := c.Trace(math.Pi / 2); math.Abs(x) > 1e-9 || math.Abs(y-(1+math.Pi/4)) > 1e-9 {
+			t.Errorf("%q at θ = π/2 is (%g, %g)", src, x, y)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/charneykaye/go-SDL-experiements/linefeed"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* measured data is plotted as a
+███████╗███████╗██████╗ ██╗███████╗███████╗
+██╔════╝██╔════╝██╔══██╗██║██╔════╝██╔════╝
+███████╗█████╗  ██████╔╝██║█████╗  ███████╗
+╚════██║██╔══╝  ██╔══██╗██║██╔══╝  ╚════██║
+███████║███████╗██║  ██║██║███████╗███████║
+╚══════╝╚══════╝╚═╝  ╚═╝╚═╝╚══════╝╚══════╝*/
+
+// Series is a list of points in plot space, in order of x
+type Series struct {
+	Name   string
+	X, Y   []float64
+	Color  uint32
+	Style  SeriesStyle
+	Window float64 // if > 0, only points this far behind the latest x are kept
+}
+
+func (s *Series) Add(x, y float64) {
+	s.X = append(s.X, x)
+	s.Y = append(s.Y, y)
+	if s.Window <= 0 {
+		return
+	}
+	n := 0
+	for n < len(s.X) && s.X[n] < x-s.Window {
+		n++
+	}
+	if n > len(s.X)/2 {
+		// drop the expired points, but only once there are many, to avoid copying every frame
+		s.X = append(s.X[:0], s.X[n:]...)
+		s.Y = append(s.Y[:0], s.Y[n:]...)
+	}
+}
+
+// Bounds are the smallest and largest finite x and y
+func (s *Series) Bounds() (xMin, xMax, yMin, yMax float64, ok bool) {
+	xMin, yMin = math.Inf(1), math.Inf(1)
+	xMax, yMax = math.Inf(-1), math.Inf(-1)
+	for i := range s.X {
+		if !finite(s.X[i]) || !finite(s.Y[i]) {
+			continue
+		}
+		xMin, xMax = math.Min(xMin, s.X[i]), math.Max(xMax, s.X[i])
+		yMin, yMax = math.Min(yMin, s.Y[i]), math.Max(yMax, s.Y[i])
+		ok = true
+	}
+	return
+}
+
+type SeriesStyle uint
+
+const (
+	STYLE_LINE SeriesStyle = iota
+	STYLE_SCATTER
+	STYLE_STEP
+)
+
+func seriesStyleNamed(name string) (SeriesStyle, bool) {
+	switch name {
+	case "line":
+		return STYLE_LINE, true
+	case "scatter":
+		return STYLE_SCATTER, true
+	case "step":
+		return STYLE_STEP, true
+	}
+	return STYLE_LINE, false
+}
+
+// RenderSeries draws the series in its style; missing values break the line
+func (r *Graph) RenderSeries(s *Series) {
+	var line []Point
+	size := seriesDotSize * r.canvas.Scale()
+	for i := range s.X {
+		if !finite(s.X[i]) || !finite(s.Y[i]) {
+			r.canvas.Polyline(line, s.Color)
+			line = line[:0]
+			continue
+		}
+		p := Point{r.View.ToScreenX(s.X[i]), r.View.ToScreenY(s.Y[i])}
+		switch s.Style {
+		case STYLE_SCATTER:
+			if p.X < -1 || p.Y < -1 || p.X > float64(r.View.W) || p.Y > float64(r.View.H) {
+				continue
+			}
+			r.canvas.FillRect(int32(p.X)-size/2, int32(p.Y)-size/2, size, size, s.Color)
+		case STYLE_STEP:
+			if len(line) > 0 {
+				line = append(line, Point{p.X, line[len(line)-1].Y})
+			}
+			line = append(line, p)
+		default:
+			line = append(line, p)
+		}
+	}
+	r.canvas.Polyline(line, s.Color)
+}
+
+// LoadSeries reads a CSV, or TSV if the file is named .tsv or its first line
+// has tabs. The first column is x and each other column is a series; with
+// only one column, x is the row number. A first row that isn't numbers names
+// the series.
+func LoadSeries(path string, style SeriesStyle) ([]*Series, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	if strings.EqualFold(filepath.Ext(path), ".tsv") || bytes.IndexByte(firstLine, '\t') >= 0 {
+		reader.Comma = '\t'
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: no rows", path)
+	}
+
+	name := filepath.Base(path)
+	var header []string
+	if _, err := strconv.ParseFloat(strings.TrimSpace(rows[0][len(rows[0])-1]), 64); err != nil {
+		header, rows = rows[0], rows[1:]
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: no data after the header", path)
+	}
+	columns := len(rows[0])
+	if header != nil {
+		columns = len(header)
+	}
+	if columns == 1 {
+		s := &Series{Name: name, Style: style}
+		for i, row := range rows {
+			s.Add(float64(i), parseValue(row, 0))
+		}
+		return []*Series{s}, nil
+	}
+	var list []*Series
+	for col := 1; col < columns; col++ {
+		s := &Series{Name: name, Style: style}
+		if header != nil {
+			s.Name = name + ": " + header[col]
+		} else if columns > 2 {
+			s.Name = name + ": " + strconv.Itoa(col)
+		}
+		for _, row := range rows {
+			s.Add(parseValue(row, 0), parseValue(row, col))
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// parseValue is NaN if the field is missing or not a number
+func parseValue(row []string, i int) float64 {
+	if i >= len(row) {
+		return math.NaN()
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+/* the graph can follow a live
+███████╗████████╗██████╗ ███████╗ █████╗ ███╗   ███╗
+██╔════╝╚══██╔══╝██╔══██╗██╔════╝██╔══██╗████╗ ████║
+███████╗   ██║   ██████╔╝█████╗  ███████║██╔████╔██║
+╚════██║   ██║   ██╔══██╗██╔══╝  ██╔══██║██║╚██╔╝██║
+███████║   ██║   ██║  ██║███████╗██║  ██║██║ ╚═╝ ██║
+╚══════╝   ╚═╝   ╚═╝  ╚═╝╚══════╝╚═╝  ╚═╝╚═╝     ╚═╝*/
+
+// StreamRow is one line of a stream: the values, at seconds since the stream opened
+type StreamRow struct {
+	T      float64
+	Values []float64
+}
+
+// OpenStream starts reading rows of numbers, separated by commas, tabs or
+// spaces, from src: "-" for stdin, "udp://host:port" or "tcp://host:port"
+// to listen on a local socket, or else a file
+func OpenStream(src string) (<-chan StreamRow, error) {
+	ch := make(chan StreamRow, streamBufferSize)
+	start := time.Now()
+	err := linefeed.Read(src, "Stream", func(line []byte) {
+		decodeStreamLine(string(line), start, ch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+func decodeStreamLine(line string, start time.Time, ch chan<- StreamRow) {
+	fields := strings.FieldsFunc(line, func(c rune) bool {
+		return c == ',' || c == '\t' || c == ' '
+	})
+	if len(fields) == 0 {
+		return
+	}
+	row := StreamRow{T: time.Since(start).Seconds()}
+	for i := range fields {
+		row.Values = append(row.Values, parseValue(fields, i))
+	}
+	ch <- row
+}
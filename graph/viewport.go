@@ -0,0 +1,36 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"github.com/charneykaye/go-SDL-experiements/plotview"
+	"sort"
+)
+
+/* the graph is seen through a
+██╗   ██╗██╗███████╗██╗    ██╗██████╗  ██████╗ ██████╗ ████████╗
+██║   ██║██║██╔════╝██║    ██║██╔══██╗██╔═══██╗██╔══██╗╚══██╔══╝
+██║   ██║██║█████╗  ██║ █╗ ██║██████╔╝██║   ██║██████╔╝   ██║
+╚██╗ ██╔╝██║██╔══╝  ██║███╗██║██╔═══╝ ██║   ██║██╔══██╗   ██║
+ ╚████╔╝ ██║███████╗╚███╔███╔╝██║     ╚██████╔╝██║  ██║   ██║
+  ╚═══╝  ╚═╝╚══════╝ ╚══╝╚══╝ ╚═╝      ╚═════╝ ╚═╝  ╚═╝   ╚═╝*/
+
+func NewViewport(w, h int32) *plotview.Viewport {
+	v := &plotview.Viewport{W: w, H: h}
+	ResetView(v)
+	return v
+}
+
+// ResetView shows the default region of plot space
+func ResetView(v *plotview.Viewport) {
+	v.XMin, v.XMax = viewDefaultXMin, viewDefaultXMax
+	v.YMin, v.YMax = viewDefaultYMin, viewDefaultYMax
+}
+
+// robustRange is the range of values between the given percentiles, which
+// keeps asymptotes from squashing the rest of a curve when fitting the view
+func robustRange(values []float64, lo, hi float64) (float64, float64) {
+	sort.Float64s(values)
+	last := float64(len(values) - 1)
+	return values[int(lo*last)], values[int(hi*last)]
+}
@@ -0,0 +1,113 @@
+/** Author: Charney Kaye */
+
+// Package linefeed reads lines as they come, from stdin, a local socket or a
+// file, for the experiments that follow a feed
+package linefeed
+
+import (
+	"bufio"
+	"bytes"
+	log "github.com/Sirupsen/logrus"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+/* lines come in on a
+███████╗███████╗███████╗██████╗
+██╔════╝██╔════╝██╔════╝██╔══██╗
+█████╗  █████╗  █████╗  ██║  ██║
+██╔══╝  ██╔══╝  ██╔══╝  ██║  ██║
+██║     ███████╗███████╗██████╔╝
+╚═╝     ╚══════╝╚══════╝╚═════╝*/
+
+// Read starts calling decode with each line read from src, which is "-" for
+// stdin, "udp://host:port" or "tcp://host:port" to listen on a local socket, or
+// else the path of a file. Blank lines are skipped, and the name is what's
+// logged when it ends.
+func Read(src, name string, decode func(line []byte)) error {
+	switch {
+	case src == "-":
+		go scanLines(os.Stdin, name, decode)
+	case strings.HasPrefix(src, "udp://"):
+		conn, err := net.ListenPacket("udp", strings.TrimPrefix(src, "udp://"))
+		if err != nil {
+			return err
+		}
+		go readPackets(conn, name, decode)
+	case strings.HasPrefix(src, "tcp://"):
+		listener, err := net.Listen("tcp", strings.TrimPrefix(src, "tcp://"))
+		if err != nil {
+			return err
+		}
+		go acceptConns(listener, name, decode)
+	default:
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer f.Close()
+			scanLines(f, name, decode)
+		}()
+	}
+	return nil
+}
+
+func scanLines(reader io.Reader, name string, decode func(line []byte)) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		decodeLine(scanner.Bytes(), decode)
+	}
+	if err := scanner.Err(); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn(name + " failed")
+		return
+	}
+	log.Info(name + " ended")
+}
+
+// each datagram holds one or more lines
+func readPackets(conn net.PacketConn, name string, decode func(line []byte)) {
+	defer conn.Close()
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn(name + " failed")
+			return
+		}
+		for _, line := range bytes.Split(buf[:n], []byte("\n")) {
+			decodeLine(line, decode)
+		}
+	}
+}
+
+// each connection is read in turn as it arrives
+func acceptConns(listener net.Listener, name string, decode func(line []byte)) {
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn(name + " failed")
+			return
+		}
+		go func() {
+			defer conn.Close()
+			scanLines(conn, name, decode)
+		}()
+	}
+}
+
+func decodeLine(line []byte, decode func(line []byte)) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	decode(line)
+}
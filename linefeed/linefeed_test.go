@@ -0,0 +1,72 @@
+/** Author: Charney Kaye */
+
+package linefeed
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// collect the lines read from src, until want of them have come or a second has gone by
+func collect(t *testing.T, src string, send func(), want int) []string {
+	ch := make(chan string, 16)
+	if err := Read(src, "Test", func(line []byte) { ch <- string(line) }); err != nil {
+		t.Fatal(err)
+	}
+	send()
+	var lines []string
+	for len(lines) < want {
+		select {
+		case line := <-ch:
+			lines = append(lines, line)
+		case <-time.After(time.Second):
+			t.Fatalf("read %q from %s, want %d lines", lines, src, want)
+		}
+	}
+	return lines
+}
+
+// freePort is a local address nobody is listening on
+func freePort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.txt")
+	if err := os.WriteFile(path, []byte("one\n\n  \ntwo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if lines := collect(t, path, func() {}, 2); lines[0] != "one" || lines[1] != "two" {
+		t.Errorf("read %q from a file", lines)
+	}
+
+	for _, network := range []string{"udp", "tcp"} {
+		addr := freePort(t)
+		lines := collect(t, network+"://"+addr, func() {
+			conn, err := net.Dial(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			// two lines at once, and a blank one
+			if _, err := conn.Write([]byte("one\n\ntwo\n")); err != nil {
+				t.Fatal(err)
+			}
+		}, 2)
+		if lines[0] != "one" || lines[1] != "two" {
+			t.Errorf("read %q over %s", lines, network)
+		}
+	}
+
+	if err := Read(filepath.Join(t.TempDir(), "missing.txt"), "Test", func([]byte) {}); err == nil {
+		t.Error("read a missing file")
+	}
+}
@@ -0,0 +1,33 @@
+/** Author: Charney Kaye */
+
+package plotview
+
+import (
+	"testing"
+)
+
+func TestDrawText(t *testing.T) {
+	var pixels, maxX, maxY int32
+	DrawText("Ab", 10, 20, 2, func(x, y, w, h int32) {
+		if w != 2 || h != 2 || x < 10 || y < 20 {
+			t.Fatalf("drew a %dx%d pixel at %d, %d", w, h, x, y)
+		}
+		pixels++
+		if x > maxX {
+			maxX = x
+		}
+		if y > maxY {
+			maxY = y
+		}
+	})
+	if pixels == 0 || maxX >= 10+TextWidth("Ab", 2) || maxY >= 20+FontHeight*2 {
+		t.Errorf("drew %d pixels out to %d, %d", pixels, maxX, maxY)
+	}
+	// anything it can't draw is a ?
+	var drawn, question int
+	DrawText("é", 0, 0, 1, func(x, y, w, h int32) { drawn++ })
+	DrawText("?", 0, 0, 1, func(x, y, w, h int32) { question++ })
+	if drawn != question || TextWidth("é", 1) != FontAdvance {
+		t.Errorf("é is %d pixels and %d wide, ? is %d pixels", drawn, TextWidth("é", 1), question)
+	}
+}
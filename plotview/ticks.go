@@ -0,0 +1,56 @@
+/** Author: Charney Kaye */
+
+package plotview
+
+import (
+	"math"
+	"strconv"
+)
+
+/* scales are marked at round numbers by
+████████╗██╗ ██████╗██╗  ██╗███████╗
+╚══██╔══╝██║██╔════╝██║ ██╔╝██╔════╝
+   ██║   ██║██║     █████╔╝ ███████╗
+   ██║   ██║██║     ██╔═██╗ ╚════██║
+   ██║   ██║╚██████╗██║  ██╗███████║
+   ╚═╝   ╚═╝ ╚═════╝╚═╝  ╚═╝╚══════╝*/
+
+// Ticks are the multiples of a nice step between min and max, aiming for about n of them
+func Ticks(min, max float64, n int) (step float64, ticks []float64) {
+	step = NiceStep((max - min) / float64(n))
+	for k := math.Ceil(min / step); k*step <= max; k++ {
+		ticks = append(ticks, k*step)
+	}
+	return
+}
+
+// NiceStep rounds up to the next 1, 2 or 5 times a power of ten
+func NiceStep(raw float64) float64 {
+	pow := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*pow {
+			return m * pow
+		}
+	}
+	return 10 * pow
+}
+
+// TickLabel formats a tick value with just enough decimals for the step
+func TickLabel(v, step float64) string {
+	if math.Abs(v) < step*1e-9 {
+		v = 0
+	}
+	decimals := int(math.Max(0, -math.Floor(math.Log10(step))))
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// GuideBrightness picks out the axes, and every fifth guide
+func GuideBrightness(v, step float64) float64 {
+	switch k := math.Abs(math.Round(v / step)); {
+	case k == 0:
+		return 0.5
+	case math.Mod(k, 5) == 0:
+		return 0.25
+	}
+	return 0.15
+}
@@ -0,0 +1,44 @@
+/** Author: Charney Kaye */
+
+package plotview
+
+import (
+	"testing"
+)
+
+func TestNiceStep(t *testing.T) {
+	for _, c := range []struct{ raw, want float64 }{
+		{1, 1},
+		{1.5, 2},
+		{3, 5},
+		{7, 10},
+		{0.03, 0.05},
+		{250, 500},
+	} {
+		if got := NiceStep(c.raw); got != c.want {
+			t.Errorf("%g steps to %g, want %g", c.raw, got, c.want)
+		}
+	}
+}
+
+func TestTicks(t *testing.T) {
+	step, ticks := Ticks(-10, 10, 10)
+	if step != 2 || len(ticks) != 11 || ticks[0] != -10 {
+		t.Fatalf("ticks %v every %g", ticks, step)
+	}
+	for _, c := range []struct {
+		v, step float64
+		want    string
+	}{
+		{0.30000000000000004, 0.1, "0.3"},
+		{-1e-17, 0.1, "0.0"},
+		{20, 5, "20"},
+	} {
+		if got := TickLabel(c.v, c.step); got != c.want {
+			t.Errorf("%v every %v is labelled %q, want %q", c.v, c.step, got, c.want)
+		}
+	}
+	if GuideBrightness(0, 2) <= GuideBrightness(10, 2) || GuideBrightness(10, 2) <= GuideBrightness(2, 2) {
+		t.Error("the axes and every fifth guide aren't picked out")
+	}
+}
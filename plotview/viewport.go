@@ -0,0 +1,103 @@
+/** Author: Charney Kaye */
+
+package plotview
+
+import (
+	"math"
+)
+
+var (
+	MinSpan   float64 = 1e-9 // zoom in no further, relative to the numbers in view
+	MaxSpan   float64 = 1e12 // or out
+	FitMargin float64 = 0.05 // of the span either side, fitting the view
+)
+
+/* plot space is seen through a
+██╗   ██╗██╗███████╗██╗    ██╗██████╗  ██████╗ ██████╗ ████████╗
+██║   ██║██║██╔════╝██║    ██║██╔══██╗██╔═══██╗██╔══██╗╚══██╔══╝
+██║   ██║██║█████╗  ██║ █╗ ██║██████╔╝██║   ██║██████╔╝   ██║
+╚██╗ ██╔╝██║██╔══╝  ██║███╗██║██╔═══╝ ██║   ██║██╔══██╗   ██║
+ ╚████╔╝ ██║███████╗╚███╔███╔╝██║     ╚██████╔╝██║  ██║   ██║
+  ╚═══╝  ╚═╝╚══════╝ ╚══╝╚══╝ ╚═╝      ╚═════╝ ╚═╝  ╚═╝   ╚═╝*/
+
+// Viewport maps the visible region of plot space onto W x H pixels, Top
+// pixels down the window, y up
+type Viewport struct {
+	XMin, XMax float64
+	YMin, YMax float64
+	W, H, Top  int32
+}
+
+func (v *Viewport) ToScreenX(x float64) float64 {
+	return (x - v.XMin) / (v.XMax - v.XMin) * float64(v.W)
+}
+
+func (v *Viewport) ToScreenY(y float64) float64 {
+	return float64(v.Top) + (v.YMax-y)/(v.YMax-v.YMin)*float64(v.H)
+}
+
+func (v *Viewport) FromScreenX(px float64) float64 {
+	return v.XMin + px/float64(v.W)*(v.XMax-v.XMin)
+}
+
+func (v *Viewport) FromScreenY(py float64) float64 {
+	return v.YMax - (py-float64(v.Top))/float64(v.H)*(v.YMax-v.YMin)
+}
+
+// Contains is whether the pixel px, py is within the viewport
+func (v *Viewport) Contains(px, py int32) bool {
+	return px >= 0 && px < v.W && py >= v.Top && py < v.Top+v.H
+}
+
+// Pan moves the view so that plot space follows a drag of dx, dy pixels
+func (v *Viewport) Pan(dx, dy int32) {
+	sx := float64(dx) / float64(v.W) * (v.XMax - v.XMin)
+	sy := float64(dy) / float64(v.H) * (v.YMax - v.YMin)
+	v.XMin, v.XMax = v.XMin-sx, v.XMax-sx
+	v.YMin, v.YMax = v.YMin+sy, v.YMax+sy
+}
+
+// Zoom magnifies by factor, keeping the plot point under pixel px, py still
+func (v *Viewport) Zoom(px, py int32, factor float64) {
+	x0, y0 := v.FromScreenX(float64(px)), v.FromScreenY(float64(py))
+	xMin, xMax := x0-(x0-v.XMin)/factor, x0+(v.XMax-x0)/factor
+	yMin, yMax := y0-(y0-v.YMin)/factor, y0+(v.YMax-y0)/factor
+	if !spanOK(xMax-xMin, x0) || !spanOK(yMax-yMin, y0) {
+		return
+	}
+	v.XMin, v.XMax, v.YMin, v.YMax = xMin, xMax, yMin, yMax
+}
+
+// ZoomX magnifies x alone by factor, keeping the plot point under pixel px still
+func (v *Viewport) ZoomX(px int32, factor float64) {
+	x0 := v.FromScreenX(float64(px))
+	xMin, xMax := x0-(x0-v.XMin)/factor, x0+(v.XMax-x0)/factor
+	if !spanOK(xMax-xMin, x0) {
+		return
+	}
+	v.XMin, v.XMax = xMin, xMax
+}
+
+// FitX shows inputs from min to max, with a margin
+func (v *Viewport) FitX(min, max float64) {
+	v.XMin, v.XMax = fitSpan(min, max)
+}
+
+// FitY shows outputs from min to max, with a margin
+func (v *Viewport) FitY(min, max float64) {
+	v.YMin, v.YMax = fitSpan(min, max)
+}
+
+// fitSpan adds a margin either side, after widening a flat span to be visible
+func fitSpan(min, max float64) (float64, float64) {
+	if max-min < MinSpan*math.Max(1, math.Abs(min)) {
+		min, max = min-1, max+1
+	}
+	m := (max - min) * FitMargin
+	return min - m, max + m
+}
+
+// spanOK keeps zoom within the precision of float64
+func spanOK(span, at float64) bool {
+	return span > MinSpan*math.Max(1, math.Abs(at)) && span < MaxSpan
+}
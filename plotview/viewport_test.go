@@ -0,0 +1,41 @@
+/** Author: Charney Kaye */
+
+package plotview
+
+import (
+	"math"
+	"testing"
+)
+
+func TestViewport(t *testing.T) {
+	v := &Viewport{XMin: -10, XMax: 10, YMin: -1, YMax: 1, W: 600, H: 592, Top: 100}
+	if v.ToScreenX(0) != 300 || v.ToScreenY(1) != 100 || v.ToScreenY(-1) != 692 {
+		t.Fatalf("0, 1 and -1 are at %g, %g and %g", v.ToScreenX(0), v.ToScreenY(1), v.ToScreenY(-1))
+	}
+	if v.Contains(300, 50) || !v.Contains(300, 150) || v.Contains(600, 150) {
+		t.Error("contains pixels outside, or not those inside")
+	}
+	x0, y0 := v.FromScreenX(100), v.FromScreenY(150)
+	v.Zoom(100, 150, 2)
+	if math.Abs(v.FromScreenX(100)-x0) > 1e-12 || math.Abs(v.FromScreenY(150)-y0) > 1e-12 || math.Abs(v.XMax-v.XMin-10) > 1e-12 {
+		t.Fatalf("zoomed to %+v, moving the point under the mouse", v)
+	}
+	v.Pan(60, 0)
+	if math.Abs(v.FromScreenX(160)-x0) > 1e-12 || math.Abs(v.FromScreenY(150)-y0) > 1e-12 {
+		t.Fatalf("panned to %+v, not following the mouse", v)
+	}
+	v.ZoomX(160, 2)
+	if math.Abs(v.FromScreenX(160)-x0) > 1e-12 || v.XMax-v.XMin != 5 || v.YMax-v.YMin != 1 {
+		t.Fatalf("zoomed x to %+v", v)
+	}
+	// no further than float64 can tell apart
+	v.XMin, v.XMax = 1e6, 1e6+1e-4
+	v.ZoomX(0, 1e6)
+	if span := v.XMax - v.XMin; span < 1e-5 {
+		t.Errorf("zoomed in to a span of %g", span)
+	}
+	v.FitY(3, 3)
+	if v.YMin >= 2 || v.YMax <= 4 {
+		t.Errorf("fit 3 to 3 as %g to %g", v.YMin, v.YMax)
+	}
+}
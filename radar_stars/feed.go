@@ -0,0 +1,69 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/charneykaye/go-SDL-experiements/linefeed"
+	"math"
+)
+
+/* the radar can watch targets from a
+███████╗███████╗███████╗██████╗
+██╔════╝██╔════╝██╔════╝██╔══██╗
+█████╗  █████╗  █████╗  ██║  ██║
+██╔══╝  ██╔══╝  ██╔══╝  ██║  ██║
+██║     ███████╗███████╗██████╔╝
+╚═╝     ╚══════╝╚══════╝╚═════╝*/
+
+// Target is one line of the feed, e.g.
+//
+//	{"id":"a1","range":42.5,"bearing":270,"speed":1.5,"heading":90}
+//
+// Sending the same id again updates the target.
+type Target struct {
+	ID      string  `json:"id"`
+	Range   float64 `json:"range"`             // radarRangeMax is the edge of the scope
+	Bearing float64 `json:"bearing"`           // degrees clockwise from north
+	Speed   float64 `json:"speed,omitempty"`   // range units per second
+	Heading float64 `json:"heading,omitempty"` // degrees clockwise from north
+	/* private */
+	seenMs uint32
+}
+
+// Position extrapolates the target along its velocity since it was last seen,
+// returning range and bearing (degrees).
+func (t *Target) Position(nowMs uint32) (float64, float64) {
+	if t.Speed == 0 {
+		return t.Range, t.Bearing
+	}
+	dt := float64(nowMs-t.seenMs) / 1000
+	bs, bc := math.Sincos(t.Bearing * degToRad)
+	hs, hc := math.Sincos(t.Heading * degToRad)
+	east := t.Range*bs + t.Speed*dt*hs
+	north := t.Range*bc + t.Speed*dt*hc
+	return math.Hypot(east, north), math.Mod(math.Atan2(east, north)/degToRad+360, 360)
+}
+
+// OpenFeed starts reading targets from src, which is "-" for stdin,
+// "udp://host:port" or "tcp://host:port" for a local socket, or else the path
+// of a JSON-lines file.
+func OpenFeed(src string) (<-chan Target, error) {
+	ch := make(chan Target, feedBufferSize)
+	err := linefeed.Read(src, "Feed", func(line []byte) {
+		var t Target
+		if err := json.Unmarshal(line, &t); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"line":  string(line),
+			}).Warn("Skipped bad feed line")
+			return
+		}
+		ch <- t
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
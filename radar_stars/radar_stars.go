@@ -3,7 +3,9 @@
 package main
 
 import (
+	"flag"
 	log "github.com/Sirupsen/logrus"
+	"github.com/charneykaye/go-SDL-experiements/audiofeed"
 	"github.com/veandco/go-sdl2/sdl"
 	"math"
 	"math/rand"
@@ -17,7 +19,44 @@ var starRadius int32 = 4
 var starBrightnessDecay float64 = 0.0015
 var starBrightnessThreshold float64 = 0.05 // below this gets recycled
 var sweepDurationMs float64 = 10000
+var sweepMode string = "clockwise"
+var sweepSectorFrom, sweepSectorTo float64 = 300, 60 // bearings, scanned clockwise from one to the other
+var sweepBeams int = 3                               // for the multi-beam mode
+var sweepVariation float64 = 0.8                     // variable speed ranges over 1 ± this
+var sweepVariationMs float64 = 7000
 var numStars int = 10000
+var radarFeed string               // empty for random blips, see OpenFeed
+var radarRangeMax float64 = 100    // target range at the edge of the scope
+var targetTimeoutMs uint32 = 30000 // forget targets that haven't been updated
+var feedBufferSize int = 1024
+var sweepAudioBoost float64 = 3 // the sweep turns up to 1 + this times as fast to loud music
+var audioSource string          // empty for none, see audiofeed.Open
+var scopeShowBeam, scopeShowRings, scopeShowBearings bool = true, true, true
+var scopeUnitScale float64 = 1 // display units per range unit
+var scopeUnitName string = ""
+var scopeRings int = 4 // roughly, rings are spaced at round numbers
+var scopeTickDeg, scopeLabelDeg int = 10, 30
+var scopeTickLen float64 = 5
+var scopeBeamWidth float64 = 0.5 // radians
+var scopeBeamLines int = 24
+var scopeBeamBrightness float64 = 0.4
+var scopeGridBrightness float64 = 0.25
+var scopeLabelBrightness float64 = 0.5
+var simTargets int = 0      // simulate this many moving targets instead of reading a feed
+var simMaxSpeed float64 = 2 // range units per second
+var simUpdateMs int = 1000
+var trackShow bool = true
+var trackMaxSpeed float64 = 2       // range units per second, the fastest a target is expected to move
+var trackGate float64 = 2           // range units around where a track could be, for the error in a plot
+var trackSpreadFloor float64 = 0.75 // of trackMaxSpeed, the least a track's velocity might be off by, so it can turn
+var trackSmoothing float64 = 0.5    // weight of the newest velocity measurement
+var trackHistory int = 12
+var trackCoastSweeps float64 = 3
+var trackTrailMs float64 = 60000
+var trackTrailBrightness float64 = 0.6
+var trackVectorSec float64 = 10 // length of the velocity vector, in seconds of travel
+var trackVectorBrightness float64 = 0.8
+var trackDotSize int32 = 2
 
 /* the smallest type of thing is a
 ███████╗████████╗ █████╗ ██████╗
@@ -68,9 +107,15 @@ func (d starSlice) Less(i, j int) bool {
 ██║  ██║██║  ██║██████╔╝██║  ██║██║  ██║
 ╚═╝  ╚═╝╚═╝  ╚═╝╚═════╝ ╚═╝  ╚═╝╚═╝  ╚═╝*/
 
-func NewRadar() *Radar {
+func NewRadar(feed <-chan Target, mode SweepMode) *Radar {
 	r := &Radar{
 		SweepPerTick: twoPi / sweepDurationMs,
+		Mode:         mode,
+		sweepDir:     1,
+		feed:         feed,
+		targets:      make(map[string]*Target),
+		scope:        NewScope(),
+		tracker:      NewTracker(),
 	}
 	r.Initialize()
 	r.lastMs = sdl.GetTicks()
@@ -79,16 +124,27 @@ func NewRadar() *Radar {
 
 type Radar struct {
 	SweepPerTick float64
+	Mode         SweepMode
+	Audio        *audiofeed.Audio // nil unless the sweep follows music
 	lastMs       uint32
 	winWidth     int32
-	NowMx        float64
-	NowMy        float64
 	NowSweep     float64
+	LastSweep    float64
+	Beams        []Beam
 	/* private */
-	m_Stars starSlice
+	m_Stars  starSlice
+	sweepDir float64 // of the sector sweep, from the next frame
+	feed     <-chan Target
+	targets  map[string]*Target
+	scope    *Scope
+	tracker  *Tracker
 }
 
 func (r *Radar) Initialize() {
+	if r.feed != nil {
+		// Stars are only born when the sweep passes a target
+		return
+	}
 	// Create stars
 	for i := 0; i < numStars; i++ {
 		s := &Star{}
@@ -101,31 +157,220 @@ func (r *Radar) RenderToSurface(surface *sdl.Surface) {
 	// First, sort the stars (by brightness) for optimal rendering
 	sort.Sort(r.m_Stars)
 	r.Life()
+	r.scope.RenderToSurface(surface, r.Beams)
+	live := r.m_Stars[:0]
 	for _, star := range r.m_Stars {
 		star.RenderToSurface(surface)
-		if !star.Life() {
+		if star.Life() {
+			live = append(live, star)
+		} else if r.feed == nil {
 			r.BirthStar(star)
+			live = append(live, star)
 		}
 	}
+	r.m_Stars = live
+	r.tracker.RenderToSurface(surface, r.lastMs)
 }
 
 func (r *Radar) Life() {
 	nowMs := sdl.GetTicks()
-	r.NowSweep += r.SweepPerTick * float64(nowMs-r.lastMs)
+	dt := float64(nowMs - r.lastMs)
+	if r.Audio != nil {
+		r.Audio.Life(nowMs)
+		dt *= 1 + sweepAudioBoost*r.Audio.Kicked(r.Audio.Level)
+	}
+	r.Sweep(nowMs, dt)
 	r.lastMs = nowMs
-	if r.NowSweep > twoPi {
-		r.NowSweep -= twoPi
+	if r.feed != nil {
+		r.ReceiveTargets(nowMs)
+		r.DetectTargets(nowMs)
+		r.tracker.Life(nowMs)
+	}
+}
+
+// Sweep moves NowSweep dt ms according to the sweep mode, and aims the beams
+func (r *Radar) Sweep(nowMs uint32, dt float64) {
+	r.LastSweep = r.NowSweep
+	var dir float64 // the way the beam moves this frame
+	switch r.Mode {
+	case SWEEP_COUNTER:
+		dir = -1
+		r.NowSweep -= r.SweepPerTick * dt
+	case SWEEP_VARIABLE:
+		dir = 1
+		r.NowSweep += r.SweepPerTick * dt * (1 + sweepVariation*math.Sin(twoPi*float64(nowMs)/sweepVariationMs))
+	case SWEEP_SECTOR:
+		from := bearingToSweep(sweepSectorFrom)
+		width := wrapAngle(bearingToSweep(sweepSectorTo) - from)
+		pos := wrapAngle(r.NowSweep - from)
+		if pos > width {
+			// outside the sector, e.g. just switched modes
+			pos, r.sweepDir, r.LastSweep = 0, 1, from
+		}
+		dir = r.sweepDir
+		pos += dir * r.SweepPerTick * dt
+		// it stops at an edge, and turns around from the next frame
+		if pos > width {
+			pos, r.sweepDir = width, -1
+		} else if pos < 0 {
+			pos, r.sweepDir = 0, 1
+		}
+		r.NowSweep = from + pos
+	default:
+		dir = 1
+		r.NowSweep += r.SweepPerTick * dt
+	}
+	r.NowSweep = wrapAngle(r.NowSweep)
+
+	n := 1
+	if r.Mode == SWEEP_MULTI {
+		n = sweepBeams
 	}
-	r.NowMy, r.NowMx = math.Sincos(r.NowSweep)
+	r.Beams = r.Beams[:0]
+	for i := 0; i < n; i++ {
+		offset := twoPi * float64(i) / float64(n)
+		r.Beams = append(r.Beams, Beam{
+			Now:  wrapAngle(r.NowSweep + offset),
+			Last: wrapAngle(r.LastSweep + offset),
+			Dir:  dir,
+		})
+	}
+	r.tracker.MinRevisitMs = r.RevisitMs()
+}
+
+// RevisitMs is about the shortest time between two passes of a beam over the same bearing
+func (r *Radar) RevisitMs() float64 {
+	revisit := sweepDurationMs / float64(2*len(r.Beams))
+	switch r.Mode {
+	case SWEEP_SECTOR:
+		// the beam turns around at the edges of the sector
+		return 0
+	case SWEEP_VARIABLE:
+		revisit = sweepDurationMs / (2 * (1 + sweepVariation))
+	}
+	if r.Audio != nil {
+		// the music can speed the sweep up
+		revisit /= 1 + sweepAudioBoost
+	}
+	return revisit
+}
+
+func (r *Radar) ChangeMode(mode SweepMode) {
+	r.Mode = mode
+	log.WithFields(log.Fields{
+		"mode": mode.Name(),
+	}).Info("Radar changed")
+}
+
+// ReceiveTargets drains the feed without blocking, and forgets stale targets
+func (r *Radar) ReceiveTargets(nowMs uint32) {
+	for received := true; received; {
+		select {
+		case t := <-r.feed:
+			t.seenMs = nowMs
+			r.targets[t.ID] = &t
+		default:
+			received = false
+		}
+	}
+	for id, t := range r.targets {
+		if nowMs-t.seenMs > targetTimeoutMs {
+			delete(r.targets, id)
+		}
+	}
+}
+
+// DetectTargets lights up a blip for each target the sweep passed since the last frame
+func (r *Radar) DetectTargets(nowMs uint32) {
+	for _, t := range r.targets {
+		rng, bearing := t.Position(nowMs)
+		if !r.Swept(bearingToSweep(bearing)) || rng > radarRangeMax {
+			continue
+		}
+		bs, bc := math.Sincos(bearing * degToRad)
+		p := Plot{East: rng * bs, North: rng * bc, Ms: nowMs}
+		x, y := plotToScreen(p.East, p.North)
+		r.m_Stars = append(r.m_Stars, &Star{X: x, Y: y, B: 1})
+		r.tracker.Plot(p)
+	}
+}
+
+// Swept is whether any beam passed angle a since the last frame
+func (r *Radar) Swept(a float64) bool {
+	for _, b := range r.Beams {
+		if b.Passed(a) {
+			return true
+		}
+	}
+	return false
 }
 
 func (r *Radar) BirthStar(s *Star) {
 	d := rand.Float64() * maxR
-	s.X = int32(math.Max(0, math.Min(float64(winWidth), centX+d*r.NowMx)))
-	s.Y = int32(math.Max(0, math.Min(float64(winHeight), centY+d*r.NowMy)))
+	my, mx := math.Sincos(r.Beams[rand.Intn(len(r.Beams))].Now)
+	s.X = int32(math.Max(0, math.Min(float64(winWidth), centX+d*mx)))
+	s.Y = int32(math.Max(0, math.Min(float64(winHeight), centY+d*my)))
 	s.B = 0.75 + rand.Float64()*0.25
 }
 
+/* the radar sees along each
+██████╗ ███████╗ █████╗ ███╗   ███╗
+██╔══██╗██╔════╝██╔══██╗████╗ ████║
+██████╔╝█████╗  ███████║██╔████╔██║
+██╔══██╗██╔══╝  ██╔══██║██║╚██╔╝██║
+██████╔╝███████╗██║  ██║██║ ╚═╝ ██║
+╚═════╝ ╚══════╝╚═╝  ╚═╝╚═╝     ╚═╝*/
+
+type Beam struct {
+	Now  float64
+	Last float64
+	Dir  float64 // 1 for clockwise, -1 for counter-clockwise
+}
+
+// Passed is whether the beam crossed angle a moving from Last to Now
+func (b Beam) Passed(a float64) bool {
+	if b.Dir < 0 {
+		return swept(a, b.Now, b.Last)
+	}
+	return swept(a, b.Last, b.Now)
+}
+
+type SweepMode uint
+
+const (
+	SWEEP_CLOCKWISE SweepMode = iota
+	SWEEP_COUNTER
+	SWEEP_SECTOR
+	SWEEP_VARIABLE
+	SWEEP_MULTI
+	numSweepModes
+)
+
+func (m SweepMode) Name() string {
+	switch m {
+	case SWEEP_CLOCKWISE:
+		return "clockwise"
+	case SWEEP_COUNTER:
+		return "counter"
+	case SWEEP_SECTOR:
+		return "sector"
+	case SWEEP_VARIABLE:
+		return "variable"
+	case SWEEP_MULTI:
+		return "multi"
+	}
+	return ""
+}
+
+func sweepModeNamed(name string) (SweepMode, bool) {
+	for m := SweepMode(0); m < numSweepModes; m++ {
+		if m.Name() == name {
+			return m, true
+		}
+	}
+	return SWEEP_CLOCKWISE, false
+}
+
 /* there is one radar for the whole
  ██████╗  █████╗ ███╗   ███╗███████╗
 ██╔════╝ ██╔══██╗████╗ ████║██╔════╝
@@ -206,7 +451,35 @@ func (g *Game) Initialize() {
 		}).Fatal("Failed to create screen surface")
 	}
 
-	g.m_Radar = NewRadar()
+	var feed <-chan Target
+	if simTargets > 0 {
+		feed = OpenSimulator(simTargets)
+	} else if radarFeed != "" {
+		feed, err = OpenFeed(radarFeed)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"feed":  radarFeed,
+				"error": err,
+			}).Fatal("Failed to open feed")
+		}
+	}
+	mode, ok := sweepModeNamed(sweepMode)
+	if !ok {
+		log.WithFields(log.Fields{
+			"sweep": sweepMode,
+		}).Fatal("Unknown sweep mode")
+	}
+	g.m_Radar = NewRadar(feed, mode)
+	if audioSource != "" {
+		audio, err := audiofeed.Open(audioSource)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"audio": audioSource,
+				"error": err,
+			}).Fatal("Failed to open audio analysis")
+		}
+		g.m_Radar.Audio = audiofeed.NewAudio(audio)
+	}
 
 	g.ChangeState(STATE_LOADING)
 }
@@ -295,8 +568,19 @@ func (g *Game) PollEvents() {
 		case *sdl.QuitEvent:
 			g.Stop()
 		case *sdl.KeyUpEvent:
-			if t.Keysym.Sym == sdl.K_ESCAPE {
+			switch t.Keysym.Sym {
+			case sdl.K_ESCAPE:
 				g.Stop()
+			case sdl.K_b:
+				g.m_Radar.scope.ShowBeam = !g.m_Radar.scope.ShowBeam
+			case sdl.K_r:
+				g.m_Radar.scope.ShowRings = !g.m_Radar.scope.ShowRings
+			case sdl.K_g:
+				g.m_Radar.scope.ShowBearings = !g.m_Radar.scope.ShowBearings
+			case sdl.K_t:
+				g.m_Radar.tracker.Show = !g.m_Radar.tracker.Show
+			case sdl.K_1, sdl.K_2, sdl.K_3, sdl.K_4, sdl.K_5:
+				g.m_Radar.ChangeMode(SweepMode(t.Keysym.Sym - sdl.K_1))
 			}
 		}
 	}
@@ -324,6 +608,27 @@ func (g *Game) NowMs() bool {
 ╚═╝     ╚═╝╚═╝  ╚═╝╚═╝╚═╝  ╚═══╝*/
 
 func main() {
+	flag.StringVar(&radarFeed, "feed", radarFeed, "targets as JSON lines: a file, - for stdin, or udp://host:port")
+	flag.Float64Var(&radarRangeMax, "range", radarRangeMax, "target range at the edge of the scope")
+	flag.BoolVar(&scopeShowBeam, "beam", scopeShowBeam, "draw the sweep beam (toggle with B)")
+	flag.BoolVar(&scopeShowRings, "rings", scopeShowRings, "draw range rings (toggle with R)")
+	flag.BoolVar(&scopeShowBearings, "bearings", scopeShowBearings, "draw the bearing graticule (toggle with G)")
+	flag.Float64Var(&scopeUnitScale, "unit-scale", scopeUnitScale, "display units per range unit, for ring labels")
+	flag.StringVar(&scopeUnitName, "unit", scopeUnitName, "display unit name, for ring labels")
+	flag.IntVar(&simTargets, "simulate", simTargets, "simulate this many moving targets instead of reading a feed")
+	flag.Float64Var(&trackMaxSpeed, "max-speed", trackMaxSpeed, "fastest a target is expected to move, in range units per second, to follow its track")
+	flag.BoolVar(&trackShow, "tracks", trackShow, "draw track histories, IDs and velocity vectors (toggle with T)")
+	flag.StringVar(&sweepMode, "sweep", sweepMode, "sweep mode: clockwise, counter, sector, variable or multi (switch with keys 1-5)")
+	flag.Float64Var(&sweepSectorFrom, "sector-from", sweepSectorFrom, "bearing where the sector scan starts")
+	flag.Float64Var(&sweepSectorTo, "sector-to", sweepSectorTo, "bearing where the sector scan ends, clockwise from -sector-from")
+	flag.IntVar(&sweepBeams, "beams", sweepBeams, "number of beams in the multi-beam mode")
+	flag.StringVar(&audioSource, "audio", audioSource, "speed the sweep up with music analysed by wav_store -analysis: a file, - for stdin, or udp://host:port")
+	flag.Parse()
+	if sweepBeams < 1 {
+		log.WithFields(log.Fields{
+			"beams": sweepBeams,
+		}).Fatal("Need at least one beam")
+	}
 	runtime.LockOSThread()
 	game := NewGame()
 	os.Exit(game.Start())
@@ -342,6 +647,27 @@ const (
 var centY, centX float64 = float64(winHeight) / 2, float64(winWidth) / 2
 var maxR float64 = math.Min(centY, centX) - 2*float64(starRadius)
 var twoPi float64 = math.Pi * 2
+var degToRad float64 = math.Pi / 180
+
+// bearings are clockwise from north, the sweep is clockwise from east (screen y is down)
+func bearingToSweep(bearing float64) float64 {
+	return wrapAngle(bearing*degToRad - math.Pi/2)
+}
+
+// swept is whether angle a lies in the clockwise arc (from, to]
+func swept(a, from, to float64) bool {
+	d := wrapAngle(a - from)
+	return d > 0 && d <= wrapAngle(to-from)
+}
+
+// wrapAngle returns the equivalent angle in [0, twoPi)
+func wrapAngle(a float64) float64 {
+	a = math.Mod(a, twoPi)
+	if a < 0 {
+		a += twoPi
+	}
+	return a
+}
 
 var palette = []uint32{
 	0xFF000000,
@@ -0,0 +1,43 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSectorSweep(t *testing.T) {
+	r := &Radar{SweepPerTick: twoPi / sweepDurationMs, Mode: SWEEP_SECTOR, sweepDir: 1, tracker: NewTracker()}
+	r.NowSweep = bearingToSweep(sweepSectorFrom)
+	from := bearingToSweep(sweepSectorFrom)
+	width := wrapAngle(bearingToSweep(sweepSectorTo) - from)
+	const bearings = 3600
+	passes := make([]int, bearings)
+	dt := 370.0 // ms, so the beam overshoots the edges
+	for nowMs := 0.0; nowMs < 4*sweepDurationMs; nowMs += dt {
+		r.Sweep(uint32(nowMs), dt)
+		b := r.Beams[0]
+		moved := math.Abs(r.NowSweep - r.LastSweep)
+		if moved > math.Pi {
+			moved = twoPi - moved
+		}
+		passed := 0
+		for i := range passes {
+			if a := twoPi * float64(i) / bearings; b.Passed(a) {
+				passes[i]++
+				passed++
+			}
+		}
+		// only the arc it moved across, even as it turns around
+		if limit := int(moved/twoPi*bearings) + 1; passed > limit {
+			t.Fatalf("at %gms the beam moved %.3f rad, but passed %d bearings", nowMs, moved, passed)
+		}
+	}
+	for i, n := range passes {
+		a := twoPi * float64(i) / bearings
+		if in := wrapAngle(a-from) <= width; in && n == 0 || !in && n > 0 {
+			t.Errorf("bearing %g passed %d times, inside the sector is %v", a, n, in)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"github.com/charneykaye/go-SDL-experiements/plotview"
+	"github.com/veandco/go-sdl2/sdl"
+	"math"
+	"strconv"
+)
+
+/* the blips are drawn over a
+███████╗ ██████╗ ██████╗ ██████╗ ███████╗
+██╔════╝██╔════╝██╔═══██╗██╔══██╗██╔════╝
+███████╗██║     ██║   ██║██████╔╝█████╗
+╚════██║██║     ██║   ██║██╔═══╝ ██╔══╝
+███████║╚██████╗╚██████╔╝██║     ███████╗
+╚══════╝ ╚═════╝ ╚═════╝ ╚═╝     ╚══════╝*/
+
+func NewScope() *Scope {
+	s := &Scope{}
+	s.Initialize()
+	return s
+}
+
+// Scope is the sweep beam, range rings and bearing graticule behind the blips
+type Scope struct {
+	ShowBeam     bool
+	ShowRings    bool
+	ShowBearings bool
+	/* private */
+	m_Rings      []sdl.Rect
+	m_RingLabels []scopeLabel
+	m_Ticks      []sdl.Rect
+	m_TickLabels []scopeLabel
+	m_BeamPoints []sdl.Rect
+}
+
+type scopeLabel struct {
+	X    int32
+	Y    int32
+	Text string
+}
+
+func (s *Scope) Initialize() {
+	s.ShowBeam = scopeShowBeam
+	s.ShowRings = scopeShowRings
+	s.ShowBearings = scopeShowBearings
+	s.m_BeamPoints = make([]sdl.Rect, int(maxR))
+
+	// Rings at a round number of display units, the outermost at maxR
+	full := radarRangeMax * scopeUnitScale
+	step := plotview.NiceStep(full / float64(scopeRings))
+	for v := step; v < full; v += step {
+		d := v / full * maxR
+		s.m_Rings = append(s.m_Rings, circlePoints(d)...)
+		s.m_RingLabels = append(s.m_RingLabels, scopeLabel{
+			X:    int32(centX) + 3,
+			Y:    int32(centY-d) + 2,
+			Text: strconv.FormatFloat(v, 'g', 4, 64) + scopeUnitName,
+		})
+	}
+	s.m_Rings = append(s.m_Rings, circlePoints(maxR)...)
+
+	// Ticks on the outer ring, long and labelled at every major bearing
+	for b := 0; b < 360; b += scopeTickDeg {
+		tickLen := scopeTickLen
+		if b%scopeLabelDeg == 0 {
+			tickLen *= 2
+		}
+		my, mx := math.Sincos(bearingToSweep(float64(b)))
+		for d := maxR - tickLen; d <= maxR; d++ {
+			s.m_Ticks = append(s.m_Ticks, sdl.Rect{int32(centX + d*mx), int32(centY + d*my), 1, 1})
+		}
+		if b%scopeLabelDeg == 0 {
+			text := strconv.Itoa(b)
+			for len(text) < 3 {
+				text = "0" + text
+			}
+			d := maxR - tickLen - float64(plotview.FontHeight)
+			s.m_TickLabels = append(s.m_TickLabels, scopeLabel{
+				X:    int32(centX+d*mx) - plotview.TextWidth(text, 1)/2,
+				Y:    int32(centY+d*my) - plotview.FontHeight/2,
+				Text: text,
+			})
+		}
+	}
+}
+
+func (s *Scope) RenderToSurface(surface *sdl.Surface, beams []Beam) {
+	if s.ShowRings {
+		surface.FillRects(s.m_Rings, colorBrightness(scopeGridBrightness))
+		for _, l := range s.m_RingLabels {
+			plotview.RenderText(surface, l.Text, l.X, l.Y, 1, colorBrightness(scopeLabelBrightness))
+		}
+	}
+	if s.ShowBearings {
+		surface.FillRects(s.m_Ticks, colorBrightness(scopeGridBrightness))
+		for _, l := range s.m_TickLabels {
+			plotview.RenderText(surface, l.Text, l.X, l.Y, 1, colorBrightness(scopeLabelBrightness))
+		}
+	}
+	if s.ShowBeam {
+		for _, b := range beams {
+			s.RenderBeam(surface, b)
+		}
+	}
+}
+
+// RenderBeam draws a wedge trailing the beam, fading away from its leading edge
+func (s *Scope) RenderBeam(surface *sdl.Surface, b Beam) {
+	for k := scopeBeamLines - 1; k >= 0; k-- {
+		fade := 1 - float64(k)/float64(scopeBeamLines)
+		my, mx := math.Sincos(b.Now - b.Dir*scopeBeamWidth*float64(k)/float64(scopeBeamLines))
+		for i := range s.m_BeamPoints {
+			s.m_BeamPoints[i] = sdl.Rect{int32(centX + float64(i)*mx), int32(centY + float64(i)*my), 1, 1}
+		}
+		surface.FillRects(s.m_BeamPoints, colorBrightness(scopeBeamBrightness*fade))
+	}
+}
+
+func circlePoints(r float64) (points []sdl.Rect) {
+	steps := int(twoPi*r) + 1
+	for i := 0; i < steps; i++ {
+		my, mx := math.Sincos(twoPi * float64(i) / float64(steps))
+		points = append(points, sdl.Rect{int32(centX + r*mx), int32(centY + r*my), 1, 1})
+	}
+	return
+}
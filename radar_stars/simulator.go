@@ -0,0 +1,81 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+/* targets can also come from a
+███████╗██╗███╗   ███╗██╗   ██╗██╗      █████╗ ████████╗ ██████╗ ██████╗
+██╔════╝██║████╗ ████║██║   ██║██║     ██╔══██╗╚══██╔══╝██╔═══██╗██╔══██╗
+███████╗██║██╔████╔██║██║   ██║██║     ███████║   ██║   ██║   ██║██████╔╝
+╚════██║██║██║╚██╔╝██║██║   ██║██║     ██╔══██║   ██║   ██║   ██║██╔══██╗
+███████║██║██║ ╚═╝ ██║╚██████╔╝███████╗██║  ██║   ██║   ╚██████╔╝██║  ██║
+╚══════╝╚═╝╚═╝     ╚═╝ ╚═════╝ ╚══════╝╚═╝  ╚═╝   ╚═╝    ╚═════╝ ╚═╝  ╚═╝*/
+
+// simTarget moves in a straight line, in range units east and north of the radar
+type simTarget struct {
+	ID      string
+	East    float64
+	North   float64
+	Speed   float64
+	Heading float64
+}
+
+func (t *simTarget) Birth() {
+	d := radarRangeMax * (0.2 + 0.7*rand.Float64())
+	t.North, t.East = math.Sincos(rand.Float64() * twoPi)
+	t.East *= d
+	t.North *= d
+	t.Speed = simMaxSpeed * (0.2 + 0.8*rand.Float64())
+	t.Heading = rand.Float64() * 360
+}
+
+// Life moves the target dt seconds, and rebirths it once it leaves the scope
+func (t *simTarget) Life(dt float64) {
+	hs, hc := math.Sincos(t.Heading * degToRad)
+	t.East += t.Speed * dt * hs
+	t.North += t.Speed * dt * hc
+	if math.Hypot(t.East, t.North) > radarRangeMax {
+		t.Birth()
+	}
+}
+
+func (t *simTarget) Target() Target {
+	return Target{
+		ID:      t.ID,
+		Range:   math.Hypot(t.East, t.North),
+		Bearing: math.Mod(math.Atan2(t.East, t.North)/degToRad+360, 360),
+		Speed:   t.Speed,
+		Heading: t.Heading,
+	}
+}
+
+// OpenSimulator feeds n targets moving with random headings and speeds,
+// reporting each of them every simUpdateMs like an external feed would
+func OpenSimulator(n int) <-chan Target {
+	ch := make(chan Target, feedBufferSize)
+	targets := make([]*simTarget, n)
+	for i := range targets {
+		targets[i] = &simTarget{ID: "sim-" + strconv.Itoa(i)}
+		targets[i].Birth()
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(simUpdateMs) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			for _, t := range targets {
+				ch <- t.Target()
+			}
+			<-ticker.C
+			for _, t := range targets {
+				t.Life(float64(simUpdateMs) / 1000)
+			}
+		}
+	}()
+	return ch
+}
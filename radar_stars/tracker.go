@@ -0,0 +1,174 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"github.com/charneykaye/go-SDL-experiements/plotview"
+	"github.com/veandco/go-sdl2/sdl"
+	"math"
+	"strconv"
+)
+
+/* detections are followed by a
+████████╗██████╗  █████╗  ██████╗██╗  ██╗███████╗██████╗
+╚══██╔══╝██╔══██╗██╔══██╗██╔════╝██║ ██╔╝██╔════╝██╔══██╗
+   ██║   ██████╔╝███████║██║     █████╔╝ █████╗  ██████╔╝
+   ██║   ██╔══██╗██╔══██║██║     ██╔═██╗ ██╔══╝  ██╔══██╗
+   ██║   ██║  ██║██║  ██║╚██████╗██║  ██╗███████╗██║  ██║
+   ╚═╝   ╚═╝  ╚═╝╚═╝  ╚═╝ ╚═════╝╚═╝  ╚═╝╚══════╝╚═╝  ╚═╝*/
+
+// Plot is one detection, in range units east and north of the radar
+type Plot struct {
+	East  float64
+	North float64
+	Ms    uint32
+}
+
+// Track is a run of plots believed to be the same target
+type Track struct {
+	ID      int
+	VEast   float64 // range units per second
+	VNorth  float64
+	History []Plot // oldest first, the last is the current position
+	/* private */
+	spread float64 // range units per second the velocity might be off by
+}
+
+func NewTrack(id int, p Plot) *Track {
+	// a single plot says nothing of where it's going
+	return &Track{ID: id, History: []Plot{p}, spread: trackMaxSpeed}
+}
+
+func (t *Track) Last() Plot {
+	return t.History[len(t.History)-1]
+}
+
+// Predict extrapolates the last plot along the track's velocity
+func (t *Track) Predict(nowMs uint32) (float64, float64) {
+	p := t.Last()
+	dt := float64(nowMs-p.Ms) / 1000
+	return p.East + t.VEast*dt, p.North + t.VNorth*dt
+}
+
+// Gate is how far from its prediction a plot at nowMs can be and still be this
+// target: as far as it could have strayed from its velocity since the last plot,
+// and trackGate more
+func (t *Track) Gate(nowMs uint32) float64 {
+	dt := float64(nowMs-t.Last().Ms) / 1000
+	return trackGate + t.spread*dt
+}
+
+func (t *Track) Update(p Plot) {
+	last := t.Last()
+	dt := float64(p.Ms-last.Ms) / 1000
+	if dt > 0 {
+		vEast := (p.East - last.East) / dt
+		vNorth := (p.North - last.North) / dt
+		if len(t.History) == 1 {
+			t.VEast, t.VNorth = vEast, vNorth
+		} else {
+			// the steadier it goes, the tighter its gate, but never so tight
+			// that a turn loses it
+			t.spread += trackSmoothing * (math.Hypot(vEast-t.VEast, vNorth-t.VNorth) - t.spread)
+			t.spread = math.Max(t.spread, trackSpreadFloor*trackMaxSpeed)
+			t.VEast += trackSmoothing * (vEast - t.VEast)
+			t.VNorth += trackSmoothing * (vNorth - t.VNorth)
+		}
+	}
+	t.History = append(t.History, p)
+	if len(t.History) > trackHistory {
+		t.History = t.History[1:]
+	}
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		Show:   trackShow,
+		nextID: 1,
+	}
+}
+
+// Tracker assigns each plot to the nearest track predicted within its gate,
+// or else starts a new track
+type Tracker struct {
+	Show         bool
+	MinRevisitMs float64 // plots closer in time than this are from the same pass of the beam
+	/* private */
+	m_Tracks []*Track
+	nextID   int
+}
+
+func (k *Tracker) Plot(p Plot) {
+	var nearest *Track
+	nearestD := math.Inf(1)
+	for _, t := range k.m_Tracks {
+		if last := t.Last().Ms; p.Ms == last || float64(p.Ms-last) < k.MinRevisitMs {
+			// already seen on this pass
+			continue
+		}
+		e, n := t.Predict(p.Ms)
+		if d := math.Hypot(p.East-e, p.North-n); d < t.Gate(p.Ms) && d < nearestD {
+			nearest, nearestD = t, d
+		}
+	}
+	if nearest != nil {
+		nearest.Update(p)
+		return
+	}
+	k.m_Tracks = append(k.m_Tracks, NewTrack(k.nextID, p))
+	k.nextID++
+}
+
+// Life drops tracks that have gone unseen for trackCoastSweeps sweeps
+func (k *Tracker) Life(nowMs uint32) {
+	live := k.m_Tracks[:0]
+	for _, t := range k.m_Tracks {
+		if float64(nowMs-t.Last().Ms) < trackCoastSweeps*sweepDurationMs {
+			live = append(live, t)
+		}
+	}
+	k.m_Tracks = live
+}
+
+func (k *Tracker) RenderToSurface(surface *sdl.Surface, nowMs uint32) {
+	if !k.Show {
+		return
+	}
+	dot := sdl.Rect{0, 0, trackDotSize, trackDotSize}
+	for _, t := range k.m_Tracks {
+		// history trail, fading with age
+		for _, p := range t.History[:len(t.History)-1] {
+			b := trackTrailBrightness * (1 - float64(nowMs-p.Ms)/trackTrailMs)
+			if b <= 0 {
+				continue
+			}
+			dot.X, dot.Y = plotToScreen(p.East, p.North)
+			dot.X -= trackDotSize / 2
+			dot.Y -= trackDotSize / 2
+			surface.FillRect(&dot, colorBrightness(b))
+		}
+		// velocity vector from the current position
+		last := t.Last()
+		x0, y0 := plotToScreen(last.East, last.North)
+		x1, y1 := plotToScreen(last.East+t.VEast*trackVectorSec, last.North+t.VNorth*trackVectorSec)
+		surface.FillRects(linePoints(x0, y0, x1, y1), colorBrightness(trackVectorBrightness))
+		plotview.RenderText(surface, strconv.Itoa(t.ID), x0+starRadius+2, y0-plotview.FontHeight, 1, colorBrightness(trackVectorBrightness))
+	}
+}
+
+// plotToScreen converts range units east and north of the radar to pixels
+func plotToScreen(east, north float64) (int32, int32) {
+	return int32(centX + east/radarRangeMax*maxR), int32(centY - north/radarRangeMax*maxR)
+}
+
+func linePoints(x0, y0, x1, y1 int32) (points []sdl.Rect) {
+	steps := int32(math.Max(math.Abs(float64(x1-x0)), math.Abs(float64(y1-y0))))
+	for i := int32(0); i <= steps; i++ {
+		f := 1.0
+		if steps > 0 {
+			f = float64(i) / float64(steps)
+		}
+		points = append(points, sdl.Rect{x0 + int32(f*float64(x1-x0)), y0 + int32(f*float64(y1-y0)), 1, 1})
+	}
+	return
+}
@@ -0,0 +1,75 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+	k := NewTracker()
+	k.MinRevisitMs = sweepDurationMs / 2
+	// two targets abreast, at full speed, seen once a sweep
+	ids := map[int]bool{}
+	for i := 0; i < 10; i++ {
+		ms := uint32(float64(i) * sweepDurationMs)
+		east := trackMaxSpeed * float64(ms) / 1000
+		k.Plot(Plot{East: east, North: 0, Ms: ms})
+		k.Plot(Plot{East: east, North: 40, Ms: ms})
+	}
+	for _, tr := range k.m_Tracks {
+		ids[tr.ID] = true
+		if len(tr.History) != 10 || math.Abs(tr.VEast-trackMaxSpeed) > 1e-9 || tr.VNorth != 0 {
+			t.Errorf("track %d has %d plots going %g, %g", tr.ID, len(tr.History), tr.VEast, tr.VNorth)
+		}
+	}
+	if len(ids) != 2 {
+		t.Fatalf("two targets made %d tracks", len(ids))
+	}
+	// once it's steady, the gate closes in on the prediction, as far as it
+	// could have turned
+	tr := k.m_Tracks[0]
+	ms := tr.Last().Ms + uint32(sweepDurationMs)
+	steady := trackGate + trackSpreadFloor*trackMaxSpeed*sweepDurationMs/1000
+	if gate := tr.Gate(ms); math.Abs(gate-steady) > 1e-9 {
+		t.Errorf("steady track's gate is %g, want %g", gate, steady)
+	}
+	e, n := tr.Predict(ms)
+	off := steady + trackGate
+	k.Plot(Plot{East: e, North: n - off, Ms: ms})
+	if len(k.m_Tracks) != 3 {
+		t.Errorf("a plot %g off a steady track joined it", off)
+	}
+	// a new track could be going anywhere
+	fresh := k.m_Tracks[2]
+	if gate := fresh.Gate(ms + uint32(sweepDurationMs)); gate != trackGate+trackMaxSpeed*sweepDurationMs/1000 {
+		t.Errorf("new track's gate is %g", gate)
+	}
+	k.Plot(Plot{East: e - trackMaxSpeed*sweepDurationMs/1000, North: n - off, Ms: ms + uint32(sweepDurationMs)})
+	if len(k.m_Tracks) != 3 || len(fresh.History) != 2 {
+		t.Errorf("a new track lost its target going back the way it came")
+	}
+}
+
+func TestTrackerTurn(t *testing.T) {
+	k := NewTracker()
+	// half speed east, then square round to the north
+	speed := trackMaxSpeed / 2
+	east, north := 0.0, 0.0
+	for i := 0; i < 10; i++ {
+		ms := uint32(float64(i) * sweepDurationMs)
+		k.Plot(Plot{East: east, North: north, Ms: ms})
+		if i < 5 {
+			east += speed * sweepDurationMs / 1000
+		} else {
+			north += speed * sweepDurationMs / 1000
+		}
+	}
+	if len(k.m_Tracks) != 1 || len(k.m_Tracks[0].History) != 10 {
+		t.Fatalf("a target turning 90 degrees made %d tracks", len(k.m_Tracks))
+	}
+	if tr := k.m_Tracks[0]; math.Abs(tr.VEast) > 0.2 || math.Abs(tr.VNorth-speed) > 0.2 {
+		t.Errorf("turned track is going %g, %g, want 0, %g", tr.VEast, tr.VNorth, speed)
+	}
+}
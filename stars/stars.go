@@ -3,8 +3,11 @@
 package main
 
 import (
+	"flag"
 	log "github.com/Sirupsen/logrus"
+	"github.com/charneykaye/go-SDL-experiements/audiofeed"
 	"github.com/veandco/go-sdl2/sdl"
+	"math"
 	"math/rand"
 	"os"
 	"runtime"
@@ -13,9 +16,14 @@ import (
 
 var winWidth, winHeight int32 = 800, 600
 var starRadius int32 = 3
-var starBrightnessDecay float64 = 0.001
 var starBrightnessThreshold float64 = 0.05
+var starAttack float64 = 0.2                         // attack-decay: fraction of the period spent rising
+var starScintillation float64 = 0.02                 // random-walk: largest brightness step per frame
+var starPeriodMin, starPeriodMax float64 = 200, 1200 // frames
+var starTwinkle string = "linear"
 var numStars int = 20000
+var starAudioBirth float64 = 0.02 // chance each frame of a faded star being born again, at full intensity
+var audioSource string            // empty for none, see audiofeed.Open
 
 /* the smallest type of thing is a
 ███████╗████████╗ █████╗ ██████╗
@@ -29,12 +37,25 @@ type Star struct {
 	X int32
 	Y int32
 	B float64
+	/* twinkle */
+	Twinkle Twinkle
+	Peak    float64 // brightness at birth, or at the top of the envelope
+	Phase   float64 // radians
+	Period  float64 // frames
+	T       float64 // frames since birth
+	Dark    bool    // faded, waiting to be born again
 }
 
 func (s *Star) Birth() {
 	s.X = int32(float64(winWidth) * rand.Float64())
 	s.Y = int32(float64(winHeight) * rand.Float64())
 	s.B = rand.Float64()
+	s.Peak = s.B
+	s.Phase = rand.Float64() * twoPi
+	s.Period = starPeriodMin + rand.Float64()*(starPeriodMax-starPeriodMin)
+	s.T = 0
+	s.Dark = false
+	s.Twinkle = twinkleFor(starTwinkle)
 }
 
 func (s *Star) RenderToSurface(surface *sdl.Surface) {
@@ -42,11 +63,97 @@ func (s *Star) RenderToSurface(surface *sdl.Surface) {
 	surface.FillRect(&sBox, colorBrightness(s.B))
 }
 
-func (s *Star) Life() {
-	s.B -= starBrightnessDecay
-	if s.B < starBrightnessThreshold {
-		s.Birth()
+// Life ages the star, and once it has faded, gives it this chance of being
+// born again each frame
+func (s *Star) Life(birth float64) {
+	if !s.Dark {
+		s.T++
+		s.Dark = !s.Twinkle(s)
+	}
+	if s.Dark {
+		s.B = 0
+		if rand.Float64() < birth {
+			s.Birth()
+		}
+	}
+}
+
+/* each star shines according to its
+████████╗██╗    ██╗██╗███╗   ██╗██╗  ██╗██╗     ███████╗
+╚══██╔══╝██║    ██║██║████╗  ██║██║ ██╔╝██║     ██╔════╝
+   ██║   ██║ █╗ ██║██║██╔██╗ ██║█████╔╝ ██║     █████╗
+   ██║   ██║███╗██║██║██║╚██╗██║██╔═██╗ ██║     ██╔══╝
+   ██║   ╚███╔███╔╝██║██║ ╚████║██║  ██╗███████╗███████╗
+   ╚═╝    ╚══╝╚══╝ ╚═╝╚═╝  ╚═══╝╚═╝  ╚═╝╚══════╝╚══════╝*/
+
+// Twinkle sets the brightness of a star for its current age (T),
+// and returns false once the star has faded and should be reborn.
+type Twinkle func(s *Star) bool
+
+var twinkleModels = map[string]Twinkle{
+	"linear":      twinkleLinear,
+	"exponential": twinkleExponential,
+	"attack":      twinkleAttackDecay,
+	"pulse":       twinklePulse,
+	"scintillate": twinkleScintillate,
+}
+
+// twinkleFor returns the named model; "mixed" picks one at random for each star
+func twinkleFor(name string) Twinkle {
+	if name == "mixed" {
+		return twinkleMixed[rand.Intn(len(twinkleMixed))]
+	}
+	if t, ok := twinkleModels[name]; ok {
+		return t
+	}
+	return twinkleLinear
+}
+
+var twinkleMixed = []Twinkle{
+	twinkleLinear,
+	twinkleExponential,
+	twinkleAttackDecay,
+	twinklePulse,
+	twinkleScintillate,
+}
+
+// saw-tooth fade from the birth brightness to nothing over the period; each
+// star starts Phase into the fade, and is reborn once it's too faint
+func twinkleLinear(s *Star) bool {
+	s.B = s.Peak * (1 - s.T/s.Period - s.Phase/twoPi)
+	return s.B >= starBrightnessThreshold
+}
+
+// fade from the birth brightness by starBrightnessThreshold of it each period;
+// each star starts Phase into the fade, and is reborn once it's too faint
+func twinkleExponential(s *Star) bool {
+	s.B = s.Peak * math.Pow(starBrightnessThreshold, s.T/s.Period+s.Phase/twoPi)
+	return s.B >= starBrightnessThreshold
+}
+
+// rise to Peak over the attack fraction of the period, then fall back to dark;
+// each star starts Phase into the cycle, and is reborn once it's been around it
+func twinkleAttackDecay(s *Star) bool {
+	p := math.Mod(s.T/s.Period+s.Phase/twoPi, 1)
+	if p < starAttack {
+		s.B = s.Peak * p / starAttack
+	} else {
+		s.B = s.Peak * (1 - (p-starAttack)/(1-starAttack))
 	}
+	return s.T < s.Period
+}
+
+// sinusoidal pulse that never dies
+func twinklePulse(s *Star) bool {
+	s.B = s.Peak * (0.5 + 0.5*math.Sin(twoPi*s.T/s.Period+s.Phase))
+	return true
+}
+
+// random walk, reborn once it wanders below the threshold; it has no cycle,
+// so it's the one model that leaves Phase and Period alone
+func twinkleScintillate(s *Star) bool {
+	s.B = clampBrightness(s.B + (rand.Float64()*2-1)*starScintillation)
+	return s.B >= starBrightnessThreshold
 }
 
 type starSlice []*Star
@@ -87,6 +194,7 @@ type Game struct {
 	Name string
 	/* private: Stars */
 	m_Stars starSlice
+	m_Audio *audiofeed.Audio // nil unless stars are born to music
 	/* private */
 	m_State StateEnum
 	nowMs   uint32
@@ -153,6 +261,17 @@ func (g *Game) Initialize() {
 		g.m_Stars = append(g.m_Stars, s)
 	}
 
+	if audioSource != "" {
+		feed, err := audiofeed.Open(audioSource)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"audio": audioSource,
+				"error": err,
+			}).Fatal("Failed to open audio analysis")
+		}
+		g.m_Audio = audiofeed.NewAudio(feed)
+	}
+
 	g.ChangeState(STATE_LOADING)
 }
 
@@ -199,9 +318,15 @@ func (g *Game) Render() {
 func (g *Game) RenderStarsToScreenSurface() int {
 	// First, sort the stars (by brightness) for optimal rendering
 	sort.Sort(g.m_Stars)
+	birth := 1.0
+	if g.m_Audio != nil {
+		// faded stars are born again more often the louder the highs
+		g.m_Audio.Life(g.nowMs)
+		birth = starAudioBirth * g.m_Audio.Kicked(g.m_Audio.Highs())
+	}
 	for _, star := range g.m_Stars {
 		star.RenderToSurface(g.sdlScreenSurface)
-		star.Life()
+		star.Life(birth)
 	}
 	return 0
 }
@@ -279,6 +404,14 @@ func (g *Game) NowMs() bool {
 ╚═╝     ╚═╝╚═╝  ╚═╝╚═╝╚═╝  ╚═══╝*/
 
 func main() {
+	flag.StringVar(&starTwinkle, "twinkle", starTwinkle, "star brightness model: linear, exponential, attack, pulse, scintillate or mixed")
+	flag.StringVar(&audioSource, "audio", audioSource, "follow music analysed by wav_store -analysis: a file, - for stdin, or udp://host:port")
+	flag.Parse()
+	if _, ok := twinkleModels[starTwinkle]; !ok && starTwinkle != "mixed" {
+		log.WithFields(log.Fields{
+			"twinkle": starTwinkle,
+		}).Fatal("Unknown twinkle model")
+	}
 	runtime.LockOSThread()
 	game := NewGame()
 	os.Exit(game.Start())
@@ -313,6 +446,12 @@ var palette = []uint32{
 	0xFFFFFFFF,
 }
 
+var twoPi float64 = math.Pi * 2
+
 func colorBrightness(b float64) uint32 {
-	return palette[int(b*float64(15))]
+	return palette[int(clampBrightness(b)*float64(15))]
+}
+
+func clampBrightness(b float64) float64 {
+	return math.Max(0, math.Min(1, b))
 }
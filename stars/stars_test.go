@@ -0,0 +1,91 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTwinkleAttackDecay(t *testing.T) {
+	for _, phase := range []float64{0, math.Pi / 2, math.Pi, 6} {
+		s := &Star{Twinkle: twinkleAttackDecay, Peak: 0.8, Phase: phase, Period: 100}
+		// as far into its cycle as its phase, from birth
+		start := math.Mod(phase/twoPi, 1)
+		frames, peak := 0, 0.0
+		for !s.Dark {
+			s.Life(0)
+			frames++
+			p := math.Mod(start+s.T/s.Period, 1)
+			want := s.Peak * p / starAttack
+			if p >= starAttack {
+				want = s.Peak * (1 - (p-starAttack)/(1-starAttack))
+			}
+			if !s.Dark && math.Abs(s.B-want) > 1e-9 {
+				t.Fatalf("phase %g at frame %d is %g, want %g", phase, frames, s.B, want)
+			}
+			peak = math.Max(peak, s.B)
+		}
+		// once around the cycle, as without a phase, peaking within a frame of Peak
+		if frames != int(s.Period) || peak > s.Peak || peak < s.Peak*(1-1/(starAttack*s.Period)) {
+			t.Errorf("phase %g lived %d frames peaking at %g, want %g at %g", phase, frames, peak, s.Period, s.Peak)
+		}
+	}
+	// stars born together don't twinkle together
+	a := &Star{Twinkle: twinkleAttackDecay, Peak: 1, Period: 100}
+	b := &Star{Twinkle: twinkleAttackDecay, Peak: 1, Period: 100, Phase: math.Pi}
+	a.Life(0)
+	b.Life(0)
+	if a.B == b.B {
+		t.Errorf("phases 0 and π are both %g", a.B)
+	}
+}
+
+func TestTwinkleFades(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		twinkle Twinkle
+		fade    func(p float64) float64 // of the peak, p periods in
+	}{
+		{"linear", twinkleLinear, func(p float64) float64 { return 1 - p }},
+		{"exponential", twinkleExponential, func(p float64) float64 { return math.Pow(starBrightnessThreshold, p) }},
+	} {
+		for _, period := range []float64{100, 400} {
+			lived := map[float64]int{}
+			for _, phase := range []float64{0, math.Pi} {
+				s := &Star{Twinkle: c.twinkle, Peak: 0.8, Phase: phase, Period: period}
+				frames := 0
+				for !s.Dark {
+					s.Life(0)
+					frames++
+					want := s.Peak * c.fade(s.T/s.Period+phase/twoPi)
+					if !s.Dark && math.Abs(s.B-want) > 1e-9 {
+						t.Fatalf("%s over %g at phase %g, frame %d is %g, want %g", c.name, period, phase, frames, s.B, want)
+					}
+				}
+				if want := s.Peak * c.fade(s.T/s.Period+phase/twoPi); want >= starBrightnessThreshold {
+					t.Errorf("%s over %g at phase %g went dark at %g", c.name, period, phase, want)
+				}
+				lived[phase] = frames
+			}
+			// faded for as long as its period, less however far in it started
+			if lived[0] > int(period) || lived[0] < int(period/2) || math.Abs(float64(lived[math.Pi]-lived[0])+period/2) > 1 {
+				t.Errorf("%s over %g lived %d frames from phase 0 and %d from π", c.name, period, lived[0], lived[math.Pi])
+			}
+		}
+	}
+}
+
+func TestTwinkleScintillate(t *testing.T) {
+	s := &Star{Twinkle: twinkleScintillate, B: 0.5, Peak: 0.5, Period: 100}
+	for frames := 0; !s.Dark && frames < 100000; frames++ {
+		b := s.B
+		s.Life(0)
+		if !s.Dark && (math.Abs(s.B-b) > starScintillation || s.B > 1) {
+			t.Fatalf("frame %d stepped from %g to %g", frames, b, s.B)
+		}
+	}
+	if !s.Dark {
+		t.Errorf("a random walk never wandered below %g", starBrightnessThreshold)
+	}
+}
@@ -0,0 +1,295 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/charneykaye/go-SDL-experiements/audiofeed"
+	"io"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/* what we hear is listened to by the
+ █████╗ ███╗   ██╗ █████╗ ██╗  ██╗   ██╗███████╗███████╗██████╗
+██╔══██╗████╗  ██║██╔══██╗██║  ╚██╗ ██╔╝██╔════╝██╔════╝██╔══██╗
+███████║██╔██╗ ██║███████║██║   ╚████╔╝ ███████╗█████╗  ██████╔╝
+██╔══██║██║╚██╗██║██╔══██║██║    ╚██╔╝  ╚════██║██╔══╝  ██╔══██╗
+██║  ██║██║ ╚████║██║  ██║███████╗██║   ███████║███████╗██║  ██║
+╚═╝  ╚═╝╚═╝  ╚═══╝╚═╝  ╚═╝╚══════╝╚═╝   ╚══════╝╚══════╝╚═╝  ╚═╝*/
+
+var (
+	analysisFrames  int     = 1024 // between analyses
+	analysisBuffers int     = 64   // of the output stream waiting to be analysed
+	analysisFloorDb float64 = -60  // level 0; 0dB is level 1
+	analysisBeatHz  float64 = 150  // beats are onsets below this
+	analysisBeatGap float64 = 0.25 // seconds, the least between beats
+	analysisBeats   int     = 8    // intervals the tempo is found from
+	onsetHistory    float64 = 1.5  // seconds of flux an onset stands out from
+	onsetDeviations float64 = 1.5  // above the mean flux, to be an onset
+)
+
+// the spectrum is split into bands between these, in Hz
+var analysisBandEdges = []float64{20, 150, 400, 1000, 2500, 6000, 16000}
+
+// OpenAnalysisOutputs opens each of a comma separated list of places to send
+// analyses: - for stdout, or udp://host:port
+func OpenAnalysisOutputs(dests string) ([]io.Writer, error) {
+	var outs []io.Writer
+	for _, dest := range strings.Split(dests, ",") {
+		switch {
+		case dest == "-":
+			outs = append(outs, os.Stdout)
+		case strings.HasPrefix(dest, "udp://"):
+			conn, err := net.Dial("udp", strings.TrimPrefix(dest, "udp://"))
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, conn)
+		default:
+			return nil, fmt.Errorf("not - or udp://host:port: %q", dest)
+		}
+	}
+	return outs, nil
+}
+
+func StartAnalyser(channels, rate int, outs []io.Writer) *Analyser {
+	bins := fftSize/2 + 1
+	binHz := float64(rate) / float64(fftSize)
+	a := &Analyser{
+		Channels: channels,
+		Rate:     rate,
+		outs:     outs,
+		Capturer: NewCapturer(analysisBuffers, int(audioBufferFrames)*channels),
+		done:     make(chan struct{}),
+		fft:      NewFFT(fftSize),
+		ring:     make([]float64, fftSize),
+		mono:     make([]float64, fftSize),
+		db:       make([]float64, bins),
+		last:     make([]float64, bins),
+		beatBins: minInt(bins, int(math.Ceil(analysisBeatHz/binHz))),
+		onsets:   newOnsetDetector(rate),
+		lows:     newOnsetDetector(rate),
+	}
+	for i := 1; i < len(analysisBandEdges); i++ {
+		from := minInt(bins-1, int(math.Ceil(analysisBandEdges[i-1]/binHz)))
+		to := minInt(bins, int(math.Ceil(analysisBandEdges[i]/binHz)))
+		a.bands = append(a.bands, [2]int{from, maxInt(from+1, to)})
+	}
+	for i := range a.last {
+		a.last[i] = analysisFloorDb
+	}
+	a.latest.Bands = make([]float64, len(a.bands))
+	go a.run()
+	return a
+}
+
+// Analyser listens to the output stream from its own goroutine, so the audio
+// callback never waits on it, and sends each analysis to its outputs as a
+// line of JSON
+type Analyser struct {
+	Channels int
+	Rate     int // frames per second
+	*Capturer
+	/* private */
+	outs   []io.Writer
+	done   chan struct{}
+	mu     sync.Mutex
+	latest audiofeed.Analysis
+	/* private: only touched by run */
+	fft      *FFT
+	ring     []float64 // the latest fftSize frames, mixed to mono
+	mono     []float64 // the same, in order
+	db       []float64 // of each bin of their spectrum
+	last     []float64 // of the analysis before
+	bands    [][2]int  // from and to bins
+	beatBins int
+	frames   int     // analysed so far
+	hop      int     // frames since the last analysis
+	sumSq    float64 // of their samples
+	onsets   *onsetDetector
+	lows     *onsetDetector
+	beatAt   float64   // seconds
+	beats    []float64 // intervals between the latest of them
+}
+
+// Latest is the last analysis made
+func (a *Analyser) Latest() audiofeed.Analysis {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	latest := a.latest
+	latest.Bands = append([]float64(nil), latest.Bands...)
+	return latest
+}
+
+// Close stops analysing; Capture must not be called after
+func (a *Analyser) Close() {
+	a.end()
+	<-a.done
+	if dropped := a.Dropped(); dropped > 0 {
+		log.WithFields(log.Fields{
+			"dropped": dropped,
+		}).Warn("Analyser fell behind")
+	}
+	for _, out := range a.outs {
+		if c, ok := out.(io.Closer); ok && out != os.Stdout {
+			c.Close()
+		}
+	}
+}
+
+func (a *Analyser) run() {
+	defer close(a.done)
+	a.each(a.Analyse)
+}
+
+// Analyse the next samples of the stream, publishing an analysis every
+// analysisFrames of them
+func (a *Analyser) Analyse(samples []float32) {
+	for f := 0; f+a.Channels <= len(samples); f += a.Channels {
+		mono := 0.0
+		for _, s := range samples[f : f+a.Channels] {
+			mono += float64(s) / float64(a.Channels)
+			a.sumSq += float64(s) * float64(s)
+		}
+		a.ring[a.frames%len(a.ring)] = mono
+		a.frames++
+		if a.hop++; a.hop == analysisFrames {
+			a.publish(a.analyse())
+			a.hop, a.sumSq = 0, 0
+		}
+	}
+}
+
+// analyse the latest frames
+func (a *Analyser) analyse() audiofeed.Analysis {
+	an := audiofeed.Analysis{
+		Time:  float64(a.frames) / float64(a.Rate),
+		RMS:   math.Sqrt(a.sumSq / float64(a.hop*a.Channels)),
+		Bands: make([]float64, len(a.bands)),
+	}
+	an.Level = dbToLevel(20 * math.Log10(an.RMS))
+	n := copy(a.mono, a.ring[a.frames%len(a.ring):])
+	copy(a.mono[n:], a.ring)
+	a.fft.Decibels(a.mono, a.db)
+	for i, band := range a.bands {
+		power := 0.0
+		for _, db := range a.db[band[0]:band[1]] {
+			power += math.Pow(10, db/10)
+		}
+		// the Hann window spreads each tone's power over 1.5 bins
+		an.Bands[i] = dbToLevel(10 * math.Log10(power/1.5))
+	}
+	// spectral flux: how much louder each bin got, from the floor up
+	low := 0.0
+	for k, db := range a.db {
+		db = math.Max(db, analysisFloorDb)
+		if rise := db - a.last[k]; rise > 0 {
+			an.Flux += rise
+			if k < a.beatBins {
+				low += rise
+			}
+		}
+		a.last[k] = db
+	}
+	an.Flux /= float64(len(a.db))
+	low /= float64(maxInt(1, a.beatBins))
+	an.Onset = a.onsets.detect(an.Flux)
+	if a.lows.detect(low) && an.Time-a.beatAt >= analysisBeatGap {
+		an.Beat = true
+		if a.beatAt > 0 {
+			a.beats = append(a.beats, an.Time-a.beatAt)
+			if len(a.beats) > analysisBeats {
+				a.beats = a.beats[1:]
+			}
+		}
+		a.beatAt = an.Time
+	}
+	if len(a.beats) >= analysisBeats/2 {
+		// the mean of the middle half of the intervals, leaving out missed
+		// and extra beats
+		intervals := append([]float64(nil), a.beats...)
+		sort.Float64s(intervals)
+		middle := intervals[len(intervals)/4 : len(intervals)-len(intervals)/4]
+		sum := 0.0
+		for _, interval := range middle {
+			sum += interval
+		}
+		an.BPM = math.Round(60 * float64(len(middle)) / sum)
+	}
+	return an
+}
+
+// publish an analysis as the latest, and send it to every output
+func (a *Analyser) publish(an audiofeed.Analysis) {
+	a.mu.Lock()
+	a.latest = an
+	a.mu.Unlock()
+	line, err := json.Marshal(an)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("Failed to encode analysis")
+		return
+	}
+	line = append(line, '\n')
+	for _, out := range a.outs {
+		// datagrams are sent whether or not anyone is listening
+		out.Write(line)
+	}
+}
+
+// dbToLevel is dB from analysisFloorDb to 0 as 0 to 1
+func dbToLevel(db float64) float64 {
+	if math.IsNaN(db) {
+		return 0
+	}
+	return math.Max(0, math.Min(1, 1-db/analysisFloorDb))
+}
+
+func newOnsetDetector(rate int) *onsetDetector {
+	return &onsetDetector{
+		history: make([]float64, maxInt(2, int(onsetHistory*float64(rate)/float64(analysisFrames)))),
+	}
+}
+
+// onsetDetector finds where a flux rises onsetDeviations above its mean over
+// the last onsetHistory
+type onsetDetector struct {
+	/* private */
+	history []float64
+	next    int
+	filled  bool
+	above   bool // the flux was over the threshold last time
+}
+
+func (d *onsetDetector) detect(flux float64) bool {
+	history := d.history
+	if !d.filled {
+		history = d.history[:d.next]
+	}
+	mean, sq := 0.0, 0.0
+	for _, h := range history {
+		mean += h
+		sq += h * h
+	}
+	over := false
+	if n := float64(len(history)); n > 1 {
+		mean /= n
+		deviation := math.Sqrt(math.Max(0, sq/n-mean*mean))
+		over = flux > mean+onsetDeviations*deviation && flux > 0
+	}
+	d.history[d.next] = flux
+	if d.next = (d.next + 1) % len(d.history); d.next == 0 {
+		d.filled = true
+	}
+	onset := over && !d.above
+	d.above = over
+	return onset
+}
@@ -0,0 +1,85 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/charneykaye/go-SDL-experiements/audiofeed"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestAnalyser(t *testing.T) {
+	channels, rate := 2, 44100
+	var out bytes.Buffer
+	a := StartAnalyser(channels, rate, []io.Writer{&out})
+	samples := make([]float32, int(audioBufferFrames)*channels)
+	for i := range samples {
+		samples[i] = 0.5
+	}
+	captures := 0
+	if n := testing.AllocsPerRun(analysisBuffers/2, func() {
+		a.Capture(samples)
+		captures++
+	}); n != 0 {
+		t.Errorf("capture allocates %v times", n)
+	}
+	a.Close()
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if want := (captures - a.Dropped()) * int(audioBufferFrames) / analysisFrames; len(lines) != want {
+		t.Fatalf("%d analyses of %d buffers, %d dropped; want %d", len(lines), captures, a.Dropped(), want)
+	}
+	var an audiofeed.Analysis
+	if err := json.Unmarshal(lines[len(lines)-1], &an); err != nil {
+		t.Fatal(err)
+	}
+	// 0.5 is -6dB
+	if an.RMS != 0.5 || an.Level < 0.89 || an.Level > 0.91 {
+		t.Errorf("RMS %g at level %g, want 0.5 and 0.9", an.RMS, an.Level)
+	}
+}
+
+func TestAnalyserTempo(t *testing.T) {
+	rate, bpm, tone := 44100, 120.0, 1500.0
+	a := StartAnalyser(1, rate, nil)
+	defer a.Close()
+	// a thump below analysisBeatHz every beat, over a steady tone
+	beat := int(60 / bpm * float64(rate))
+	samples := make([]float32, analysisFrames)
+	beats, bpmAt, loud := 0, 0.0, make([]float64, len(analysisBandEdges)-1)
+	for f := 0; f < 20*rate; f += analysisFrames {
+		for i := range samples {
+			at := float64(f+i) / float64(rate)
+			since := float64((f+i)%beat) / float64(rate)
+			samples[i] = float32(0.3*math.Sin(2*math.Pi*tone*at) +
+				0.6*math.Exp(-since/0.03)*math.Sin(2*math.Pi*60*since))
+		}
+		a.Analyse(samples)
+		an := a.Latest()
+		if an.Beat {
+			beats++
+		}
+		bpmAt = an.BPM
+		for i, level := range an.Bands {
+			loud[i] += level
+		}
+	}
+	if want := 20 * int(bpm) / 60; beats < want-2 || beats > want {
+		t.Errorf("%d beats in 20s, want %d", beats, want)
+	}
+	if math.Abs(bpmAt-bpm) > 2 {
+		t.Errorf("%g BPM, want %g", bpmAt, bpm)
+	}
+	loudest := 0
+	for i := range loud {
+		if loud[i] > loud[loudest] {
+			loudest = i
+		}
+	}
+	// 1500Hz is between the 4th and 5th edges
+	if loudest != 3 {
+		t.Errorf("band %d is loudest of %v, want 3", loudest, loud)
+	}
+}
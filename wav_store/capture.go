@@ -0,0 +1,81 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"sync/atomic"
+)
+
+/* the recorder and analyser listen by
+ ██████╗ █████╗ ██████╗ ████████╗██╗   ██╗██████╗ ███████╗
+██╔════╝██╔══██╗██╔══██╗╚══██╔══╝██║   ██║██╔══██╗██╔════╝
+██║     ███████║██████╔╝   ██║   ██║   ██║██████╔╝█████╗
+██║     ██╔══██║██╔═══╝    ██║   ██║   ██║██╔══██╗██╔══╝
+╚██████╗██║  ██║██║        ██║   ╚██████╔╝██║  ██║███████╗
+ ╚═════╝╚═╝  ╚═╝╚═╝        ╚═╝    ╚═════╝ ╚═╝  ╚═╝╚══════╝*/
+
+func NewCapturer(buffers, size int) *Capturer {
+	c := &Capturer{
+		chunks: make(chan []float32, buffers),
+		free:   make(chan []float32, buffers),
+	}
+	// each the size of a buffer of the audio device, so Capture needn't allocate
+	for i := 0; i < buffers; i++ {
+		c.free <- make([]float32, size)
+	}
+	return c
+}
+
+// Capturer hands buffers of the output stream from the audio callback to a
+// goroutine of their own, through a pool of them, so the callback never waits
+type Capturer struct {
+	/* private */
+	dropped int64 // atomically, buffers lost because the goroutine fell behind
+	chunks  chan []float32
+	free    chan []float32
+}
+
+// Capture copies a buffer of the output stream to be handed on; it never
+// blocks, and only allocates if the device's buffers are bigger than were
+// asked for
+func (c *Capturer) Capture(samples []float32) {
+	var chunk []float32
+	select {
+	case chunk = <-c.free:
+	default:
+		// every buffer is still waiting to be handled
+		atomic.AddInt64(&c.dropped, 1)
+		return
+	}
+	if cap(chunk) < len(samples) {
+		chunk = make([]float32, len(samples))
+	}
+	chunk = chunk[:len(samples)]
+	copy(chunk, samples)
+	select {
+	case c.chunks <- chunk:
+	default:
+		atomic.AddInt64(&c.dropped, 1)
+	}
+}
+
+// Dropped is how many buffers were lost because the goroutine fell behind
+func (c *Capturer) Dropped() int {
+	return int(atomic.LoadInt64(&c.dropped))
+}
+
+// each calls handle with every buffer captured, in order, until end
+func (c *Capturer) each(handle func(samples []float32)) {
+	for chunk := range c.chunks {
+		handle(chunk)
+		select {
+		case c.free <- chunk:
+		default:
+		}
+	}
+}
+
+// end stops each once it has handled what's left; Capture must not be called after
+func (c *Capturer) end() {
+	close(c.chunks)
+}
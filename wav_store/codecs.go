@@ -0,0 +1,217 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/jfreymuth/oggvorbis"
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"io"
+	"os"
+	"strings"
+)
+
+/* files that aren't WAV are read by other
+ ██████╗ ██████╗ ██████╗ ███████╗ ██████╗███████╗
+██╔════╝██╔═══██╗██╔══██╗██╔════╝██╔════╝██╔════╝
+██║     ██║   ██║██║  ██║█████╗  ██║     ███████╗
+██║     ██║   ██║██║  ██║██╔══╝  ██║     ╚════██║
+╚██████╗╚██████╔╝██████╔╝███████╗╚██████╗███████║
+ ╚═════╝ ╚═════╝ ╚═════╝ ╚══════╝ ╚═════╝╚══════╝*/
+
+func newFlacDecoder(f *os.File) (*flacDecoder, error) {
+	stream, err := flac.NewSeek(f)
+	if err != nil {
+		return nil, err
+	}
+	si := stream.Info
+	d := &flacDecoder{
+		info: WavInfo{
+			Format:    WAVE_FORMAT_PCM,
+			Channels:  int(si.NChannels),
+			Rate:      int(si.SampleRate),
+			Bits:      int(si.BitsPerSample),
+			ValidBits: int(si.BitsPerSample),
+			Frames:    int(si.NSamples),
+		},
+		file:   f,
+		stream: stream,
+		scale:  1 / float32(int64(1)<<(si.BitsPerSample-1)),
+	}
+	if si.NSamples == 0 {
+		d.info.Frames = -1 // not said
+	}
+	return d, nil
+}
+
+// flacDecoder reads FLAC a frame of its own at a time
+type flacDecoder struct {
+	info   WavInfo
+	file   *os.File
+	stream *flac.Stream
+	frame  *frame.Frame // being read
+	offset int          // of the next sample of each channel in it
+	skip   int          // samples of the next frame to skip, after a seek
+	scale  float32      // of its integers, to -1..1
+	eof    bool
+}
+
+func (d *flacDecoder) Info() *WavInfo {
+	return &d.info
+}
+
+func (d *flacDecoder) Read(out []float32) (int, error) {
+	channels := d.info.Channels
+	frames, n := len(out)/channels, 0
+	for n < frames && !d.eof {
+		if d.frame == nil || d.offset >= int(d.frame.BlockSize) {
+			next, err := d.stream.ParseNext()
+			if err == io.EOF {
+				d.eof = true
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+			d.frame, d.offset, d.skip = next, d.skip, 0
+		}
+		for ; d.offset < int(d.frame.BlockSize) && n < frames; d.offset++ {
+			for c, sub := range d.frame.Subframes {
+				out[n*channels+c] = float32(sub.Samples[d.offset]) * d.scale
+			}
+			n++
+		}
+	}
+	if n == 0 && d.eof {
+		return 0, io.EOF
+	}
+	return n * channels, nil
+}
+
+// SeekFrame seeks to the start of the FLAC frame it's in, then skips to it
+func (d *flacDecoder) SeekFrame(frame int) error {
+	d.frame, d.offset, d.skip = nil, 0, 0
+	if d.eof = d.info.Frames >= 0 && frame >= d.info.Frames; d.eof {
+		return nil
+	}
+	start, err := d.stream.Seek(uint64(maxInt(frame, 0)))
+	if err != nil {
+		return err
+	}
+	d.skip = maxInt(frame, 0) - int(start)
+	return nil
+}
+
+func (d *flacDecoder) Close() error {
+	return d.file.Close()
+}
+
+func newOggDecoder(f *os.File) (*oggDecoder, error) {
+	r, err := oggvorbis.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	for _, comment := range r.CommentHeader().Comments {
+		if i := strings.IndexByte(comment, '='); i > 0 {
+			tags[strings.ToUpper(comment[:i])] = comment[i+1:]
+		}
+	}
+	return &oggDecoder{
+		info: WavInfo{
+			Format:    WAVE_FORMAT_IEEE_FLOAT, // as decoded
+			Channels:  r.Channels(),
+			Rate:      r.SampleRate(),
+			Bits:      32,
+			ValidBits: 32,
+			Frames:    int(r.Length()),
+			Tags:      tags,
+		},
+		file:   f,
+		reader: r,
+	}, nil
+}
+
+// oggDecoder reads Ogg Vorbis, decoded straight to floating point
+type oggDecoder struct {
+	info   WavInfo
+	file   *os.File
+	reader *oggvorbis.Reader
+}
+
+func (d *oggDecoder) Info() *WavInfo {
+	return &d.info
+}
+
+func (d *oggDecoder) Read(out []float32) (int, error) {
+	n, err := d.reader.Read(out[:len(out)/d.info.Channels*d.info.Channels])
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (d *oggDecoder) SeekFrame(frame int) error {
+	return d.reader.SetPosition(int64(maxInt(frame, 0)))
+}
+
+func (d *oggDecoder) Close() error {
+	return d.file.Close()
+}
+
+func newMp3Decoder(f *os.File) (*mp3Decoder, error) {
+	dec, err := mp3.NewDecoder(f)
+	if err != nil {
+		return nil, err
+	}
+	d := &mp3Decoder{
+		// always decoded to 16-bit stereo
+		info:    WavInfo{Format: WAVE_FORMAT_PCM, Channels: 2, Rate: dec.SampleRate(), Bits: 16, ValidBits: 16},
+		file:    f,
+		decoder: dec,
+	}
+	d.info.Frames = int(dec.Length()) / d.info.FrameSize()
+	if dec.Length() < 0 {
+		d.info.Frames = -1
+	}
+	return d, nil
+}
+
+// mp3Decoder reads MP3, through the 16-bit samples it decodes to
+type mp3Decoder struct {
+	info    WavInfo
+	file    *os.File
+	decoder *mp3.Decoder
+	raw     []byte
+}
+
+func (d *mp3Decoder) Info() *WavInfo {
+	return &d.info
+}
+
+func (d *mp3Decoder) Read(out []float32) (int, error) {
+	size := len(out) / d.info.Channels * d.info.FrameSize()
+	if cap(d.raw) < size {
+		d.raw = make([]byte, size)
+	}
+	n, err := io.ReadFull(d.decoder, d.raw[:size])
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	frames := n / d.info.FrameSize()
+	d.info.Decode(d.raw[:frames*d.info.FrameSize()], out[:frames*d.info.Channels])
+	return frames * d.info.Channels, nil
+}
+
+func (d *mp3Decoder) SeekFrame(frame int) error {
+	_, err := d.decoder.Seek(int64(maxInt(frame, 0)*d.info.FrameSize()), io.SeekStart)
+	return err
+}
+
+func (d *mp3Decoder) Close() error {
+	return d.file.Close()
+}
@@ -0,0 +1,215 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+)
+
+/* every sound is made to fit the device by the
+ ██████╗ ██████╗ ███╗   ██╗██╗   ██╗███████╗██████╗ ████████╗███████╗██████╗
+██╔════╝██╔═══██╗████╗  ██║██║   ██║██╔════╝██╔══██╗╚══██╔══╝██╔════╝██╔══██╗
+██║     ██║   ██║██╔██╗ ██║██║   ██║█████╗  ██████╔╝   ██║   █████╗  ██████╔╝
+██║     ██║   ██║██║╚██╗██║╚██╗ ██╔╝██╔══╝  ██╔══██╗   ██║   ██╔══╝  ██╔══██╗
+╚██████╗╚██████╔╝██║ ╚████║ ╚████╔╝ ███████╗██║  ██║   ██║   ███████╗██║  ██║
+ ╚═════╝ ╚═════╝ ╚═╝  ╚═══╝  ╚═══╝  ╚══════╝╚═╝  ╚═╝   ╚═╝   ╚══════╝╚═╝  ╚═╝*/
+
+var (
+	resampleZeros   int     = 16   // zero crossings of the sinc either side
+	resamplePhases  int     = 512  // steps of the kernel table per input sample
+	resampleRolloff float64 = 0.95 // of the lower of the two Nyquist frequencies
+	resampleBeta    float64 = 8    // of the Kaiser window
+)
+
+// Convert is the sound with the given channels, at the given rate; the same
+// sound, if it already is
+func (s *Sound) Convert(channels, rate int) *Sound {
+	if s.Channels == channels && s.Rate == rate {
+		return s
+	}
+	out := &Sound{WavInfo: s.WavInfo}
+	out.Samples = Remix(s.Samples, s.Channels, channels)
+	out.Channels = channels
+	out.ChannelMask = 0
+	if s.Rate != rate {
+		out.Samples = Resample(out.Samples, channels, s.Rate, rate)
+		out.Rate = rate
+	}
+	out.Frames = len(out.Samples) / channels
+	return out
+}
+
+// Remix interleaved samples from one number of channels to another. One
+// channel goes to the first two of many; many go to one as their average;
+// otherwise the first channels are kept, and any more are mixed into all of
+// them, 3dB down
+func Remix(in []float32, from, to int) []float32 {
+	if from == to {
+		return in
+	}
+	frames := len(in) / from
+	out := make([]float32, frames*to)
+	for f := 0; f < frames; f++ {
+		src, dst := in[f*from:(f+1)*from], out[f*to:(f+1)*to]
+		switch {
+		case to == 1:
+			sum := float32(0)
+			for _, s := range src {
+				sum += s
+			}
+			dst[0] = sum / float32(from)
+		case from == 1:
+			dst[0], dst[1] = src[0], src[0]
+		default:
+			copy(dst, src)
+			for _, s := range src[minInt(from, to):] {
+				for c := range dst {
+					dst[c] += s * math.Sqrt2 / 2
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Resample interleaved samples from one rate to another, by a Kaiser-windowed
+// sinc that cuts off just below the lower Nyquist frequency of the two
+func Resample(in []float32, channels, from, to int) []float32 {
+	if from == to {
+		return in
+	}
+	r := NewResampler(channels, from, to)
+	out := make([]float32, 0, (len(in)/channels*to/from+1)*channels)
+	return r.Flush(r.Process(in, out))
+}
+
+func NewResampler(channels, from, to int) *Resampler {
+	cutoff := resampleRolloff * math.Min(1, float64(to)/float64(from))
+	r := &Resampler{
+		Channels: channels,
+		From:     from,
+		To:       to,
+		half:     float64(resampleZeros) / cutoff,
+		acc:      make([]float64, channels),
+	}
+	r.kernel = sincKernel(cutoff, r.half)
+	return r
+}
+
+// Resampler changes the rate of samples that come a piece at a time, keeping
+// as many of the last as the sinc reaches back over
+type Resampler struct {
+	Channels int
+	From     int // frames per second in
+	To       int // and out
+	/* private */
+	kernel []float64
+	half   float64   // input frames either side
+	in     []float32 // input, from frame offset on
+	offset int
+	frames int // of input so far
+	next   int // output frame
+	acc    []float64
+}
+
+// Process the next input samples, and append to out what output they complete
+func (r *Resampler) Process(in, out []float32) []float32 {
+	r.in = append(r.in, in...)
+	r.frames += len(in) / r.Channels
+	for {
+		t := r.time(r.next)
+		if int(math.Floor(t+r.half)) >= r.frames {
+			break
+		}
+		out = r.emit(t, out)
+	}
+	// drop what the next output doesn't reach back to
+	if drop := int(math.Ceil(r.time(r.next)-r.half)) - r.offset; drop > 0 {
+		drop = minInt(drop, len(r.in)/r.Channels)
+		r.in = r.in[:copy(r.in, r.in[drop*r.Channels:])]
+		r.offset += drop
+	}
+	return out
+}
+
+// Flush appends the rest of the output to out, as if the input were followed
+// by silence, and starts over
+func (r *Resampler) Flush(out []float32) []float32 {
+	for int64(r.next)*int64(r.From) < int64(r.frames)*int64(r.To) {
+		out = r.emit(r.time(r.next), out)
+	}
+	r.Reset()
+	return out
+}
+
+// Reset forgets the input, for another that isn't continuous with it
+func (r *Resampler) Reset() {
+	r.in, r.offset, r.frames, r.next = r.in[:0], 0, 0, 0
+}
+
+// time of an output frame, in input frames
+func (r *Resampler) time(frame int) float64 {
+	return float64(frame) * float64(r.From) / float64(r.To)
+}
+
+// emit the next output frame, centred at t
+func (r *Resampler) emit(t float64, out []float32) []float32 {
+	phases := float64(resamplePhases)
+	for c := range r.acc {
+		r.acc[c] = 0
+	}
+	j0 := maxInt(int(math.Ceil(t-r.half)), r.offset)
+	j1 := minInt(int(math.Floor(t+r.half)), r.frames-1)
+	for j := j0; j <= j1; j++ {
+		x := math.Abs(t-float64(j)) * phases
+		k := int(x)
+		if k+1 >= len(r.kernel) {
+			continue
+		}
+		w := r.kernel[k] + (r.kernel[k+1]-r.kernel[k])*(x-float64(k))
+		i := (j - r.offset) * r.Channels
+		for c, s := range r.in[i : i+r.Channels] {
+			r.acc[c] += float64(s) * w
+		}
+	}
+	for _, a := range r.acc {
+		out = append(out, float32(a))
+	}
+	r.next++
+	return out
+}
+
+// sincKernel is the windowed sinc from 0 to half input samples away, in
+// steps of 1/resamplePhases
+func sincKernel(cutoff, half float64) []float64 {
+	n := int(half*float64(resamplePhases)) + 2
+	kernel := make([]float64, n)
+	norm := besselI0(resampleBeta)
+	for k := range kernel {
+		x := float64(k) / float64(resamplePhases)
+		if x >= half {
+			break
+		}
+		r := x / half
+		window := besselI0(resampleBeta*math.Sqrt(1-r*r)) / norm
+		kernel[k] = cutoff * sinc(cutoff*x) * window
+	}
+	return kernel
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// besselI0 is the modified Bessel function of the first kind, of order zero
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-12; k++ {
+		term *= (x / 2 / float64(k)) * (x / 2 / float64(k))
+		sum += term
+	}
+	return sum
+}
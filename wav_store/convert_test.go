@@ -0,0 +1,88 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestResampler(t *testing.T) {
+	for _, c := range []struct{ from, to int }{
+		{44100, 48000},
+		{48000, 44100},
+		{22050, 44100},
+		{48000, 16000},
+	} {
+		in := Remix(sine(c.from, c.from/2, 440, 0.5), 1, 2)
+		whole := Resample(in, 2, c.from, c.to)
+		// as long as the input, rounded up to a whole frame
+		if want := (len(in)/2*c.to + c.from - 1) / c.from * 2; len(whole) != want {
+			t.Errorf("%d to %d: %d samples out, want %d", c.from, c.to, len(whole), want)
+		}
+		// a piece at a time, in pieces of every size, comes out the same
+		r := NewResampler(2, c.from, c.to)
+		var out []float32
+		for i, n := 0, 1; i < len(in); i, n = i+n*2, n%97+1 {
+			out = r.Process(in[i:minInt(len(in), i+n*2)], out)
+		}
+		if out = r.Flush(out); !reflect.DeepEqual(out, whole) {
+			t.Errorf("%d to %d: in pieces differs from all at once", c.from, c.to)
+		}
+		// away from the ends, where it's cut off, the sine is the same
+		margin := 2 * resampleZeros * c.to / minInt(c.from, c.to)
+		worst := 0.0
+		for f := margin; f < len(whole)/2-margin; f++ {
+			want := 0.5 * math.Sin(2*math.Pi*440*float64(f)/float64(c.to))
+			for _, v := range whole[f*2 : f*2+2] {
+				worst = math.Max(worst, math.Abs(float64(v)-want))
+			}
+		}
+		if worst > 2e-5 {
+			t.Errorf("%d to %d: the sine is out by up to %g", c.from, c.to, worst)
+		}
+		// and it starts over after a flush
+		if again := r.Flush(r.Process(in, nil)); !reflect.DeepEqual(again, whole) {
+			t.Errorf("%d to %d: differs after a flush", c.from, c.to)
+		}
+	}
+}
+
+func TestRemix(t *testing.T) {
+	h := float32(math.Sqrt2 / 2)
+	for _, c := range []struct {
+		from, to int
+		in, want []float32
+	}{
+		{1, 2, []float32{0.5, -1}, []float32{0.5, 0.5, -1, -1}},
+		{1, 4, []float32{0.5}, []float32{0.5, 0.5, 0, 0}},
+		{2, 1, []float32{0.5, 0, 1, 1}, []float32{0.25, 1}},
+		{6, 1, []float32{1, 1, 1, 1, 0, 0}, []float32{float32(4) / 6}},
+		{2, 4, []float32{0.5, -0.5}, []float32{0.5, -0.5, 0, 0}},
+		{3, 2, []float32{0.5, -0.5, 0.25}, []float32{0.5 + 0.25*h, -0.5 + 0.25*h}},
+		{2, 2, []float32{0.5, -0.5}, []float32{0.5, -0.5}},
+	} {
+		if got := Remix(c.in, c.from, c.to); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%d to %d channels: %v gave %v, want %v", c.from, c.to, c.in, got, c.want)
+		}
+	}
+}
+
+func TestSoundConvert(t *testing.T) {
+	s := &Sound{WavInfo: WavInfo{Channels: 1, Rate: 22050, Frames: 22050, ChannelMask: 0x4}}
+	s.Samples = sine(s.Rate, s.Frames, 440, 0.5)
+	if s.Convert(1, 22050) != s {
+		t.Errorf("a sound already at the channels and rate was copied")
+	}
+	out := s.Convert(2, 44100)
+	if out.Channels != 2 || out.Rate != 44100 || out.ChannelMask != 0 {
+		t.Errorf("converted to %d channels at %d, mask %#x", out.Channels, out.Rate, out.ChannelMask)
+	}
+	if out.Frames != 44100 || len(out.Samples) != out.Frames*2 {
+		t.Errorf("%d frames of %d samples, want 44100", out.Frames, len(out.Samples))
+	}
+	if s.Channels != 1 || s.Rate != 22050 || len(s.Samples) != 22050 {
+		t.Errorf("converting changed the original")
+	}
+}
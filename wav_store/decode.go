@@ -0,0 +1,95 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+/* any file we can play is read by a
+██████╗ ███████╗ ██████╗ ██████╗ ██████╗ ███████╗██████╗
+██╔══██╗██╔════╝██╔════╝██╔═══██╗██╔══██╗██╔════╝██╔══██╗
+██║  ██║█████╗  ██║     ██║   ██║██║  ██║█████╗  ██████╔╝
+██║  ██║██╔══╝  ██║     ██║   ██║██║  ██║██╔══╝  ██╔══██╗
+██████╔╝███████╗╚██████╗╚██████╔╝██████╔╝███████╗██║  ██║
+╚═════╝ ╚══════╝ ╚═════╝ ╚═════╝ ╚═════╝ ╚══════╝╚═╝  ╚═╝*/
+
+// Decoder reads the samples of a sound file a piece at a time, from -1 to 1,
+// interleaved by channel
+type Decoder interface {
+	// Info describes the samples, as stored; Frames is -1 if unknown
+	Info() *WavInfo
+	// Read as many of the next frames as fit in out, and is the number of
+	// samples read, or io.EOF after the last of them
+	Read(out []float32) (int, error)
+	// SeekFrame makes frame the next to be read
+	SeekFrame(frame int) error
+	Close() error
+}
+
+var ErrUnknownFormat = errors.New("not a WAV, FLAC, Ogg Vorbis or MP3 file")
+
+// Open a sound file with the Decoder for what its first bytes say it is,
+// whatever its name
+func Open(path string) (Decoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var head [12]byte
+	n, err := io.ReadFull(f, head[:])
+	if err == nil || err == io.ErrUnexpectedEOF || err == io.EOF {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var dec Decoder
+	switch sig := head[:n]; {
+	case n == len(head) && bytes.HasPrefix(sig, []byte("RIFF")) && bytes.HasPrefix(sig[8:], []byte("WAVE")):
+		dec, err = newWavReader(f)
+	case bytes.HasPrefix(sig, []byte("fLaC")):
+		dec, err = newFlacDecoder(f)
+	case bytes.HasPrefix(sig, []byte("OggS")):
+		dec, err = newOggDecoder(f)
+	case bytes.HasPrefix(sig, []byte("ID3")), len(sig) >= 2 && sig[0] == 0xFF && sig[1]&0xE0 == 0xE0:
+		dec, err = newMp3Decoder(f)
+	default:
+		err = ErrUnknownFormat
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return dec, nil
+}
+
+// Load decodes the whole of a sound file
+func Load(path string) (*Sound, error) {
+	dec, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+	s := &Sound{WavInfo: *dec.Info()}
+	if s.Frames > 0 {
+		s.Samples = make([]float32, 0, s.Frames*s.Channels)
+	}
+	buf := make([]float32, streamChunkFrames*s.Channels)
+	for {
+		n, err := dec.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.Samples = append(s.Samples, buf[:n]...)
+	}
+	s.Frames = len(s.Samples) / s.Channels
+	return s, nil
+}
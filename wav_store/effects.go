@@ -0,0 +1,517 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/* the mix is coloured by a chain of
+███████╗███████╗███████╗███████╗ ██████╗████████╗███████╗
+██╔════╝██╔════╝██╔════╝██╔════╝██╔════╝╚══██╔══╝██╔════╝
+█████╗  █████╗  █████╗  █████╗  ██║        ██║   ███████╗
+██╔══╝  ██╔══╝  ██╔══╝  ██╔══╝  ██║        ██║   ╚════██║
+███████╗██║     ██║     ███████╗╚██████╗   ██║   ███████║
+╚══════╝╚═╝     ╚═╝     ╚══════╝ ╚═════╝   ╚═╝   ╚══════╝*/
+
+var (
+	delayMaxSeconds float64 = 2
+	reverbCombs             = []int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617} // frames at 44.1kHz
+	reverbAllpasses         = []int{556, 441, 341, 225}
+	reverbSpread    int     = 23 // frames between the tunings of each channel
+)
+
+// Effect changes samples in place, a buffer at a time; Process is called on
+// SDL's audio thread, so it must not allocate
+type Effect interface {
+	Process(buf []float32)
+	// Set a parameter by name, e.g. "freq" of a lowpass
+	Set(param string, value float64) error
+}
+
+// EffectSpec is one line of an effects file: the kind of effect, and its
+// parameters, e.g. "lowpass freq=800 q=0.7"
+type EffectSpec struct {
+	Kind   string
+	Params map[string]float64
+}
+
+// LoadEffects reads an effects file
+func LoadEffects(path string) ([]EffectSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseEffects(f)
+}
+
+// ParseEffects reads an effect per line, in the order they're applied; blank
+// lines and those starting with # are skipped
+func ParseEffects(r io.Reader) ([]EffectSpec, error) {
+	var specs []EffectSpec
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		spec := EffectSpec{Kind: strings.ToLower(fields[0]), Params: map[string]float64{}}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("line %d: %q is not param=value", line, field)
+			}
+			v, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %v", line, kv[0], err)
+			}
+			spec.Params[strings.ToLower(kv[0])] = v
+		}
+		specs = append(specs, spec)
+	}
+	return specs, scanner.Err()
+}
+
+// NewEffect of a kind, with its default parameters
+func NewEffect(kind string, channels, rate int) (Effect, error) {
+	switch kind {
+	case "gain":
+		return &Gain{gain: 1}, nil
+	case "lowpass", "highpass", "bandpass":
+		return NewBiquad(kind, channels, rate), nil
+	case "delay":
+		return NewDelay(channels, rate), nil
+	case "reverb":
+		return NewReverb(channels, rate), nil
+	case "compressor":
+		return NewCompressor(channels, rate), nil
+	}
+	return nil, fmt.Errorf("no effect %q", kind)
+}
+
+func newEffect(spec EffectSpec, channels, rate int) (Effect, error) {
+	effect, err := NewEffect(spec.Kind, channels, rate)
+	if err != nil {
+		return nil, err
+	}
+	for param, value := range spec.Params {
+		if err := effect.Set(param, value); err != nil {
+			return nil, fmt.Errorf("%s: %v", spec.Kind, err)
+		}
+	}
+	return effect, nil
+}
+
+func NewChain(channels, rate int) *Chain {
+	return &Chain{Channels: channels, Rate: rate}
+}
+
+// Chain applies its effects to the mix in order; it can be reconfigured,
+// adjusted and bypassed while it plays
+type Chain struct {
+	Channels int
+	Rate     int // frames per second
+	/* private */
+	mu      sync.Mutex
+	specs   []EffectSpec
+	effects []Effect
+	bypass  bool
+}
+
+// Configure the chain to be the effects of specs, each with its default
+// parameters but those given. If they're the same kinds of effect in the same
+// order as it has, filters, delays and reverbs carry on ringing; otherwise
+// the chain starts again from silence
+func (c *Chain) Configure(specs []EffectSpec) error {
+	effects := make([]Effect, len(specs))
+	for i, spec := range specs {
+		var err error
+		if effects[i], err = newEffect(spec, c.Channels, c.Rate); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	same := len(specs) == len(c.specs)
+	for i := 0; same && i < len(specs); i++ {
+		same = specs[i].Kind == c.specs[i].Kind
+	}
+	if same {
+		for i, effect := range effects {
+			if r, ok := effect.(ringer); ok {
+				r.carryOn(c.effects[i])
+			}
+		}
+	}
+	c.effects, c.specs = effects, specs
+	return nil
+}
+
+// ringer is an Effect that still sounds what it heard before, and can carry
+// on from where another of its kind left off
+type ringer interface {
+	carryOn(from Effect)
+}
+
+// Bypass the effects, or not
+func (c *Chain) Bypass(bypass bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bypass = bypass
+}
+
+func (c *Chain) Bypassed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bypass
+}
+
+// Len is how many effects there are
+func (c *Chain) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.effects)
+}
+
+func (c *Chain) Process(buf []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bypass {
+		return
+	}
+	for _, effect := range c.effects {
+		effect.Process(buf)
+	}
+}
+
+func noParam(param string) error {
+	return fmt.Errorf("no param %q", param)
+}
+
+// Gain turns every sample up or down by db
+type Gain struct {
+	gain float64
+}
+
+func (g *Gain) Set(param string, value float64) error {
+	if param != "db" {
+		return noParam(param)
+	}
+	g.gain = dbToGain(value)
+	return nil
+}
+
+func (g *Gain) Process(buf []float32) {
+	for i, s := range buf {
+		buf[i] = float32(float64(s) * g.gain)
+	}
+}
+
+func NewBiquad(kind string, channels, rate int) *Biquad {
+	b := &Biquad{
+		Kind:     kind,
+		Freq:     1000,
+		Q:        math.Sqrt2 / 2,
+		channels: channels,
+		rate:     float64(rate),
+		state:    make([][2]float64, channels),
+	}
+	b.design()
+	return b
+}
+
+// Biquad is a second-order lowpass, highpass or bandpass filter, after the
+// Audio EQ Cookbook of Robert Bristow-Johnson; the bandpass peaks at 0dB
+type Biquad struct {
+	Kind string
+	Freq float64 // Hz, of the cutoff or centre
+	Q    float64
+	/* private */
+	channels   int
+	rate       float64
+	b0, b1, b2 float64
+	a1, a2     float64
+	state      [][2]float64 // of each channel, transposed direct form II
+}
+
+func (b *Biquad) Set(param string, value float64) error {
+	switch param {
+	case "freq":
+		b.Freq = value
+	case "q":
+		b.Q = value
+	default:
+		return noParam(param)
+	}
+	b.design()
+	return nil
+}
+
+// design the coefficients for Freq and Q
+func (b *Biquad) design() {
+	freq := math.Max(1, math.Min(b.Freq, b.rate*0.49))
+	q := math.Max(b.Q, 0.01)
+	w0 := 2 * math.Pi * freq / b.rate
+	cos, alpha := math.Cos(w0), math.Sin(w0)/(2*q)
+	switch b.Kind {
+	case "highpass":
+		b.b0, b.b1, b.b2 = (1+cos)/2, -(1 + cos), (1+cos)/2
+	case "bandpass":
+		b.b0, b.b1, b.b2 = alpha, 0, -alpha
+	default:
+		b.b0, b.b1, b.b2 = (1-cos)/2, 1-cos, (1-cos)/2
+	}
+	a0 := 1 + alpha
+	b.b0, b.b1, b.b2 = b.b0/a0, b.b1/a0, b.b2/a0
+	b.a1, b.a2 = -2*cos/a0, (1-alpha)/a0
+}
+
+func (b *Biquad) carryOn(from Effect) {
+	b.state = from.(*Biquad).state
+}
+
+func (b *Biquad) Process(buf []float32) {
+	for i, s := range buf {
+		z := &b.state[i%b.channels]
+		x := float64(s)
+		y := b.b0*x + z[0]
+		z[0] = b.b1*x - b.a1*y + z[1]
+		z[1] = b.b2*x - b.a2*y
+		buf[i] = float32(y)
+	}
+}
+
+func NewDelay(channels, rate int) *Delay {
+	d := &Delay{
+		Time:     0.3,
+		Feedback: 0.4,
+		Mix:      0.3,
+		channels: channels,
+		rate:     float64(rate),
+		buf:      make([]float32, int(delayMaxSeconds*float64(rate))*channels),
+	}
+	d.Set("time", d.Time)
+	return d
+}
+
+// Delay echoes what it hears Time later, and echoes the echoes, each of them
+// Feedback times as loud
+type Delay struct {
+	Time     float64 // seconds, up to delayMaxSeconds
+	Feedback float64 // 0 to under 1
+	Mix      float64 // of the echoes, over what's heard
+	/* private */
+	channels int
+	rate     float64
+	buf      []float32 // of each frame, delayMaxSeconds of them, around and around
+	at       int       // sample in buf to write next
+	lag      int       // samples back to read
+}
+
+func (d *Delay) Set(param string, value float64) error {
+	switch param {
+	case "time":
+		d.Time = math.Max(1/d.rate, math.Min(value, delayMaxSeconds))
+		d.lag = int(math.Round(d.Time*d.rate)) * d.channels
+		d.lag = minInt(maxInt(d.lag, d.channels), len(d.buf))
+	case "feedback":
+		d.Feedback = math.Max(0, math.Min(value, 0.99))
+	case "mix":
+		d.Mix = value
+	default:
+		return noParam(param)
+	}
+	return nil
+}
+
+func (d *Delay) carryOn(from Effect) {
+	old := from.(*Delay)
+	d.buf, d.at = old.buf, old.at
+}
+
+func (d *Delay) Process(buf []float32) {
+	for i, s := range buf {
+		echo := d.buf[(d.at-d.lag+len(d.buf))%len(d.buf)]
+		d.buf[d.at] = s + echo*float32(d.Feedback)
+		d.at = (d.at + 1) % len(d.buf)
+		buf[i] = s + echo*float32(d.Mix)
+	}
+}
+
+func NewReverb(channels, rate int) *Reverb {
+	r := &Reverb{Size: 0.5, Damp: 0.5, Mix: 0.25, channels: channels}
+	scale := float64(rate) / 44100
+	for c := 0; c < channels; c++ {
+		var combs []reverbComb
+		for _, frames := range reverbCombs {
+			combs = append(combs, reverbComb{buf: make([]float64, maxInt(1, int(float64(frames+c*reverbSpread)*scale)))})
+		}
+		var allpasses []reverbAllpass
+		for _, frames := range reverbAllpasses {
+			allpasses = append(allpasses, reverbAllpass{buf: make([]float64, maxInt(1, int(float64(frames+c*reverbSpread)*scale)))})
+		}
+		r.combs = append(r.combs, combs)
+		r.allpasses = append(r.allpasses, allpasses)
+	}
+	return r
+}
+
+// Reverb is Jezar's Freeverb: eight damped combs in parallel, then four
+// allpasses in series, tuned a little differently for each channel
+type Reverb struct {
+	Size float64 // of the room, 0 to 1
+	Damp float64 // of the highs, 0 to 1
+	Mix  float64 // of the reverb, over what's heard
+	/* private */
+	channels  int
+	combs     [][]reverbComb
+	allpasses [][]reverbAllpass
+}
+
+type reverbComb struct {
+	buf   []float64
+	at    int
+	store float64 // of the damping lowpass
+}
+
+type reverbAllpass struct {
+	buf []float64
+	at  int
+}
+
+func (r *Reverb) Set(param string, value float64) error {
+	switch param {
+	case "size":
+		r.Size = math.Max(0, math.Min(value, 1))
+	case "damp":
+		r.Damp = math.Max(0, math.Min(value, 1))
+	case "mix":
+		r.Mix = value
+	default:
+		return noParam(param)
+	}
+	return nil
+}
+
+func (r *Reverb) carryOn(from Effect) {
+	old := from.(*Reverb)
+	r.combs, r.allpasses = old.combs, old.allpasses
+}
+
+func (r *Reverb) Process(buf []float32) {
+	feedback, damp := 0.7+0.28*r.Size, 0.4*r.Damp
+	for i, s := range buf {
+		c := i % r.channels
+		in := float64(s) * 0.015
+		wet := 0.0
+		for k := range r.combs[c] {
+			comb := &r.combs[c][k]
+			out := comb.buf[comb.at]
+			comb.store = out*(1-damp) + comb.store*damp
+			comb.buf[comb.at] = in + comb.store*feedback
+			comb.at = (comb.at + 1) % len(comb.buf)
+			wet += out
+		}
+		for k := range r.allpasses[c] {
+			ap := &r.allpasses[c][k]
+			out := ap.buf[ap.at]
+			ap.buf[ap.at] = wet + out*0.5
+			ap.at = (ap.at + 1) % len(ap.buf)
+			wet = out - wet
+		}
+		buf[i] = float32(float64(s) + wet*3*r.Mix)
+	}
+}
+
+func NewCompressor(channels, rate int) *Compressor {
+	c := &Compressor{
+		Threshold: -18,
+		Ratio:     4,
+		channels:  channels,
+		rate:      float64(rate),
+		makeup:    1,
+	}
+	c.Set("attack", 0.005)
+	c.Set("release", 0.1)
+	return c
+}
+
+// Compressor turns down whatever goes over Threshold, so it only goes over
+// by 1/Ratio as many dB, following the loudest channel
+type Compressor struct {
+	Threshold float64 // dB
+	Ratio     float64
+	Attack    float64 // seconds
+	Release   float64 // seconds
+	Makeup    float64 // dB, turned up after
+	/* private */
+	channels int
+	rate     float64
+	attack   float64 // per frame
+	release  float64
+	makeup   float64
+	level    float64 // followed, of the loudest channel
+}
+
+func (c *Compressor) Set(param string, value float64) error {
+	switch param {
+	case "threshold":
+		c.Threshold = value
+	case "ratio":
+		c.Ratio = math.Max(1, value)
+	case "attack":
+		c.Attack = math.Max(0, value)
+		c.attack = math.Exp(-1 / math.Max(c.Attack*c.rate, 1e-9))
+	case "release":
+		c.Release = math.Max(0, value)
+		c.release = math.Exp(-1 / math.Max(c.Release*c.rate, 1e-9))
+	case "makeup":
+		c.Makeup = value
+		c.makeup = dbToGain(value)
+	default:
+		return noParam(param)
+	}
+	return nil
+}
+
+func (c *Compressor) carryOn(from Effect) {
+	c.level = from.(*Compressor).level
+}
+
+func (c *Compressor) Process(buf []float32) {
+	for f := 0; f+c.channels <= len(buf); f += c.channels {
+		frame := buf[f : f+c.channels]
+		peak := 0.0
+		for _, s := range frame {
+			peak = math.Max(peak, math.Abs(float64(s)))
+		}
+		k := c.release
+		if peak > c.level {
+			k = c.attack
+		}
+		c.level = peak + (c.level-peak)*k
+		gain := c.makeup
+		if over := gainToDb(c.level) - c.Threshold; over > 0 {
+			gain *= dbToGain(-over * (1 - 1/c.Ratio))
+		}
+		for i := range frame {
+			frame[i] = float32(float64(frame[i]) * gain)
+		}
+	}
+}
+
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func gainToDb(gain float64) float64 {
+	return 20 * math.Log10(math.Max(gain, 1e-12))
+}
@@ -0,0 +1,240 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// sine is frames of a mono sine wave at hz, as loud as amp
+func sine(rate, frames int, hz, amp float64) []float32 {
+	s := make([]float32, frames)
+	for i := range s {
+		s[i] = float32(amp * math.Sin(2*math.Pi*hz*float64(i)/float64(rate)))
+	}
+	return s
+}
+
+// sineAmplitude is how loud the sine at hz is in mono samples s
+func sineAmplitude(s []float32, rate int, hz float64) float64 {
+	var re, im float64
+	for i, v := range s {
+		p := 2 * math.Pi * hz * float64(i) / float64(rate)
+		re += float64(v) * math.Cos(p)
+		im += float64(v) * math.Sin(p)
+	}
+	return 2 * math.Hypot(re, im) / float64(len(s))
+}
+
+// energy is the sum of the squares of s
+func energy(s []float32) (e float64) {
+	for _, v := range s {
+		e += float64(v) * float64(v)
+	}
+	return
+}
+
+func TestBiquad(t *testing.T) {
+	rate := 48000
+	for _, c := range []struct {
+		kind     string
+		hz       float64
+		min, max float64 // dB
+	}{
+		{"lowpass", 1000, -3.1, -2.9}, // at the cutoff
+		{"lowpass", 100, -0.1, 0.1},
+		{"lowpass", 10000, -100, -38},
+		{"highpass", 1000, -3.1, -2.9},
+		{"highpass", 10000, -0.1, 0.1},
+		{"highpass", 100, -100, -38},
+		{"bandpass", 1000, -0.1, 0.1}, // at the centre
+		{"bandpass", 100, -100, -15},
+		{"bandpass", 10000, -100, -15},
+	} {
+		b := NewBiquad(c.kind, 1, rate)
+		buf := sine(rate, rate/2, c.hz, 0.5)
+		b.Process(buf)
+		// after it settles
+		db := 20 * math.Log10(sineAmplitude(buf[rate/4:], rate, c.hz)/0.5)
+		if db < c.min || db > c.max {
+			t.Errorf("%s of 1000Hz passes %gHz at %.2fdB, want %g to %g", c.kind, c.hz, db, c.min, c.max)
+		}
+	}
+	// channels are filtered apart
+	b := NewBiquad("lowpass", 2, rate)
+	buf := make([]float32, 2000)
+	for i := 0; i < len(buf); i += 2 {
+		buf[i] = 1
+	}
+	b.Process(buf)
+	for i := 1; i < len(buf); i += 2 {
+		if buf[i] != 0 {
+			t.Fatalf("left leaks into right at sample %d", i)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	rate := 1000
+	d := NewDelay(2, rate)
+	d.Set("time", 0.1)
+	d.Set("feedback", 0.5)
+	d.Set("mix", 1)
+	buf := make([]float32, 2*rate)
+	buf[0], buf[1] = 1, -1
+	// in pieces, as the audio device asks for them
+	d.Process(buf[:300])
+	d.Process(buf[300:])
+	for f := 0; f < rate; f++ {
+		want := 0.0
+		switch {
+		case f == 0:
+			want = 1
+		case f%100 == 0:
+			want = math.Pow(0.5, float64(f/100-1))
+		}
+		if math.Abs(float64(buf[2*f])-want) > 1e-6 || math.Abs(float64(buf[2*f+1])+want) > 1e-6 {
+			t.Fatalf("frame %d is %v, %v, want %v, %v", f, buf[2*f], buf[2*f+1], want, -want)
+		}
+	}
+}
+
+func TestReverb(t *testing.T) {
+	rate := 44100
+	impulse := func(size float64) []float32 {
+		r := NewReverb(2, rate)
+		r.Set("size", size)
+		r.Set("mix", 1)
+		buf := make([]float32, 2*2*rate)
+		buf[0], buf[1] = 1, 1
+		r.Process(buf)
+		return buf
+	}
+	at := func(buf []float32, from, to float64) []float32 {
+		return buf[2*int(from*float64(rate)) : 2*int(to*float64(rate))]
+	}
+	buf := impulse(0.5)
+	early, late, end := energy(at(buf, 0.001, 0.3)), energy(at(buf, 0.5, 0.8)), energy(at(buf, 1.7, 2))
+	if late == 0 || early <= late || late <= end {
+		t.Errorf("energy %g early, %g late and %g at the end doesn't decay", early, late, end)
+	}
+	if bigger := energy(at(impulse(1), 1.7, 2)); bigger <= end {
+		t.Errorf("a bigger room rings %g at the end, no longer than %g", bigger, end)
+	}
+	same := true
+	for i := 4000; i < 8000; i += 2 {
+		same = same && buf[i] == buf[i+1]
+	}
+	if same {
+		t.Error("both channels ring the same")
+	}
+}
+
+func TestCompressor(t *testing.T) {
+	rate := 48000
+	for _, c := range []struct {
+		level     float64
+		threshold float64
+		ratio     float64
+		makeup    float64
+		want      float64 // dB
+	}{
+		{-6, -18, 4, 0, -15},      // 12dB over, so 9dB down
+		{0, -20, 2, 0, -10},       // 20dB over, so 10dB down
+		{-6, -18, 4, 6, -9},       // and turned up after
+		{-24, -18, 4, 0, -24},     // under, so untouched
+		{-12, -18, 1, 0, -12},     // at a ratio of 1, untouched
+		{0, -30, 1000, 0, -29.97}, // limited
+	} {
+		comp := NewCompressor(1, rate)
+		comp.Set("threshold", c.threshold)
+		comp.Set("ratio", c.ratio)
+		comp.Set("makeup", c.makeup)
+		// a steady level, so the follower settles on it exactly
+		buf := make([]float32, rate/2)
+		for i := range buf {
+			buf[i] = float32(dbToGain(c.level))
+		}
+		comp.Process(buf)
+		if db := gainToDb(float64(buf[len(buf)-1])); math.Abs(db-c.want) > 0.01 {
+			t.Errorf("%gdB through threshold %g, ratio %g and makeup %g is %.2fdB, want %g", c.level, c.threshold, c.ratio, c.makeup, db, c.want)
+		}
+	}
+	// the follower takes about the attack to catch a jump
+	comp := NewCompressor(1, rate)
+	comp.Set("attack", 0.01)
+	buf := make([]float32, rate/10)
+	for i := range buf {
+		buf[i] = 1
+	}
+	comp.Process(buf)
+	if first, attacked := gainToDb(float64(buf[0])), gainToDb(float64(buf[rate/100*5])); first < -1 || attacked > -13 {
+		t.Errorf("attack goes from %.2fdB to %.2fdB after 5 of them", first, attacked)
+	}
+}
+
+func TestChainConfigure(t *testing.T) {
+	specs, err := ParseEffects(strings.NewReader(`
+# a comment
+highpass freq=80
+compressor threshold=-18 ratio=4 makeup=6
+delay time=0.375 feedback=0.35 mix=0.25
+`))
+	if err != nil || len(specs) != 3 || specs[2].Params["time"] != 0.375 {
+		t.Fatal(specs, err)
+	}
+	for _, bad := range []string{"delay time", "delay time=soon", "flanger depth=1"} {
+		specs, err := ParseEffects(strings.NewReader(bad))
+		if err == nil {
+			err = NewChain(2, 44100).Configure(specs)
+		}
+		if err == nil {
+			t.Errorf("%q configured without error", bad)
+		}
+	}
+	c := NewChain(1, 1000)
+	if err := c.Configure(specs); err != nil {
+		t.Fatal(err)
+	}
+	// ringing, then reconfigured with the delay's feedback taken out
+	buf := make([]float32, 500)
+	buf[0] = 1
+	c.Process(buf)
+	delete(specs[2].Params, "feedback")
+	specs[2].Params["time"] = 0.5
+	if err := c.Configure(specs); err != nil {
+		t.Fatal(err)
+	}
+	d := c.effects[2].(*Delay)
+	if d.Feedback != NewDelay(1, 1000).Feedback || d.Time != 0.5 {
+		t.Errorf("delay reconfigured to feedback %g, time %g; want the default, 0.5", d.Feedback, d.Time)
+	}
+	if c.effects[1].(*Compressor).Makeup != 6 {
+		t.Error("compressor lost its makeup")
+	}
+	// the echo of what was heard before still comes
+	buf = make([]float32, 500)
+	c.Process(buf)
+	if energy(buf) == 0 {
+		t.Error("delay stopped ringing when its numbers changed")
+	}
+	// other kinds of effect start again
+	if err := c.Configure([]EffectSpec{{Kind: "gain", Params: map[string]float64{"db": -6}}}); err != nil || c.Len() != 1 {
+		t.Fatal(c.Len(), err)
+	}
+	buf = []float32{0.5, 0.5}
+	c.Process(buf)
+	if math.Abs(float64(buf[0])-0.25) > 0.001 || buf[1] != buf[0] {
+		t.Errorf("-6dB of 0.5 is %v", buf)
+	}
+	c.Bypass(true)
+	buf = []float32{0.5}
+	if c.Process(buf); buf[0] != 0.5 {
+		t.Errorf("bypassed, 0.5 is %v", buf[0])
+	}
+	if n := testing.AllocsPerRun(10, func() { c.Process(buf) }); n != 0 {
+		t.Errorf("processing allocates %v times", n)
+	}
+}
@@ -0,0 +1,75 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+)
+
+/* the spectrum of the latest samples is found by a
+███████╗███████╗████████╗
+██╔════╝██╔════╝╚══██╔══╝
+█████╗  █████╗     ██║
+██╔══╝  ██╔══╝     ██║
+██║     ██║        ██║
+╚═╝     ╚═╝        ╚═╝*/
+
+// FFT is a radix-2 fast Fourier transform of N samples, N a power of two
+type FFT struct {
+	N int
+	/* private */
+	window   []float64 // Hann
+	re, im   []float64
+	cos, sin []float64 // of each twiddle angle
+	reverse  []int     // bit reversed index
+}
+
+func NewFFT(n int) *FFT {
+	f := &FFT{
+		N:       n,
+		window:  make([]float64, n),
+		re:      make([]float64, n),
+		im:      make([]float64, n),
+		cos:     make([]float64, n/2),
+		sin:     make([]float64, n/2),
+		reverse: make([]int, n),
+	}
+	bits := uint(math.Log2(float64(n)))
+	for i := 0; i < n; i++ {
+		f.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+		for b := uint(0); b < bits; b++ {
+			f.reverse[i] |= (i >> b & 1) << (bits - 1 - b)
+		}
+	}
+	for k := 0; k < n/2; k++ {
+		f.cos[k] = math.Cos(2 * math.Pi * float64(k) / float64(n))
+		f.sin[k] = -math.Sin(2 * math.Pi * float64(k) / float64(n))
+	}
+	return f
+}
+
+// Decibels of the windowed spectrum of x, relative to a full scale sine,
+// into db for each of the N/2+1 frequency bins
+func (f *FFT) Decibels(x []float64, db []float64) {
+	for i := range x {
+		f.re[f.reverse[i]], f.im[f.reverse[i]] = x[i]*f.window[i], 0
+	}
+	for size := 2; size <= f.N; size <<= 1 {
+		half, step := size/2, f.N/size
+		for start := 0; start < f.N; start += size {
+			for k := 0; k < half; k++ {
+				c, s := f.cos[k*step], f.sin[k*step]
+				a, b := start+k, start+k+half
+				tr := f.re[b]*c - f.im[b]*s
+				ti := f.re[b]*s + f.im[b]*c
+				f.re[b], f.im[b] = f.re[a]-tr, f.im[a]-ti
+				f.re[a], f.im[a] = f.re[a]+tr, f.im[a]+ti
+			}
+		}
+	}
+	// a full scale sine peaks at N/4 through the Hann window
+	fullScale := float64(f.N) / 4
+	for k := range db {
+		db[k] = math.Max(spectrumFloorDb, 20*math.Log10(math.Hypot(f.re[k], f.im[k])/fullScale))
+	}
+}
@@ -0,0 +1,353 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"math"
+	"sync"
+)
+
+/* many sounds at once, summed by the
+███╗   ███╗██╗██╗  ██╗███████╗██████╗
+████╗ ████║██║╚██╗██╔╝██╔════╝██╔══██╗
+██╔████╔██║██║ ╚███╔╝ █████╗  ██████╔╝
+██║╚██╔╝██║██║ ██╔██╗ ██╔══╝  ██╔══██╗
+██║ ╚═╝ ██║██║██╔╝ ██╗███████╗██║  ██║
+╚═╝     ╚═╝╚═╝╚═╝  ╚═╝╚══════╝╚═╝  ╚═╝*/
+
+var (
+	audioFormats = map[string]WavInfo{
+		"u8":  {Format: WAVE_FORMAT_PCM, Bits: 8},
+		"s16": {Format: WAVE_FORMAT_PCM, Bits: 16},
+		"s32": {Format: WAVE_FORMAT_PCM, Bits: 32},
+		"f32": {Format: WAVE_FORMAT_IEEE_FLOAT, Bits: 32},
+	}
+	limiterCeiling float64 = 1        // the loudest the mix may be
+	limiterRelease float64 = 0.25     // seconds for the limiter to recover
+	voiceMinPitch  float64 = 1.0 / 64 // the slowest a voice plays, so that it still ends
+)
+
+// NewMixer for an audio device of the format of stream, one of audioFormats
+func NewMixer(stream WavInfo) *Mixer {
+	return &Mixer{
+		Channels: stream.Channels,
+		Rate:     stream.Rate,
+		Effects:  NewChain(stream.Channels, stream.Rate),
+		stream:   stream,
+		gain:     1,
+		release:  1 - math.Exp(-1/(limiterRelease*float64(stream.Rate))),
+	}
+}
+
+// Mixer sums its sources and voices into the output stream, on SDL's audio
+// thread, through its effects, then a limiter that leaves the mix alone until
+// it would clip
+type Mixer struct {
+	Channels int
+	Rate     int // frames per second
+	Effects  *Chain
+	/* private */
+	mu       sync.Mutex
+	sources  []Source
+	voices   []*Voice
+	stream   WavInfo // as sent to the audio device
+	mix      []float32
+	buf      []float32
+	gain     float64 // of the limiter
+	release  float64 // per frame
+	recorder *Recorder
+	analyser *Analyser
+}
+
+// Source is a stream of samples of the mixer's channels and rate
+type Source interface {
+	// Next fills out with the next samples, and is how many of them were
+	// played, rather than silence
+	Next(out []float32) int
+}
+
+// Voice is one sound playing in a Mixer, until it ends or is stopped
+type Voice struct {
+	/* private */
+	mixer   *Mixer
+	sound   *Sound
+	volume  float64
+	pan     float64 // -1 is left only, 1 right only, 0 both at full volume
+	pitch   float64 // 1 is as recorded, 2 an octave up
+	loop    bool
+	pos     float64 // frame of the sound
+	stopped bool
+}
+
+// Spec of the audio device to play through, calling AudioCallback
+func (m *Mixer) Spec() *sdl.AudioSpec {
+	return &sdl.AudioSpec{
+		Freq:     int32(m.Rate),
+		Format:   m.format(),
+		Channels: uint8(m.Channels),
+		Samples:  audioBufferFrames,
+	}
+}
+
+func (m *Mixer) format() sdl.AudioFormat {
+	switch {
+	case m.stream.Format == WAVE_FORMAT_IEEE_FLOAT:
+		return sdl.AUDIO_F32LSB
+	case m.stream.Bits == 8:
+		return sdl.AUDIO_U8
+	case m.stream.Bits == 32:
+		return sdl.AUDIO_S32LSB
+	}
+	return sdl.AUDIO_S16LSB
+}
+
+// Stream is the format of what's sent to the audio device
+func (m *Mixer) Stream() WavInfo {
+	return m.stream
+}
+
+// Add a source, like a Player of a sound converted to the same channels and
+// rate, to be mixed in from now on
+func (m *Mixer) Add(source Source) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sources = append(m.sources, source)
+}
+
+// Play a sound from the start, mixed in until it ends, or forever if it loops;
+// it can't be played backwards, or slower than voiceMinPitch
+func (m *Mixer) Play(sound *Sound, volume, pan, pitch float64, loop bool) *Voice {
+	v := &Voice{
+		mixer:  m,
+		sound:  sound,
+		volume: volume,
+		pan:    math.Max(-1, math.Min(pan, 1)),
+		pitch:  math.Max(voiceMinPitch, pitch),
+		loop:   loop,
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.voices = append(m.voices, v)
+	return v
+}
+
+// StopAll the voices playing; sources are left alone
+func (m *Mixer) StopAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.voices {
+		v.stopped = true
+	}
+	m.voices = m.voices[:0]
+}
+
+// Voices is how many are playing
+func (m *Mixer) Voices() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.voices)
+}
+
+// Record passes everything played on to r, or stops passing it on if r is nil
+func (m *Mixer) Record(r *Recorder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recorder = r
+}
+
+// Analyse passes everything played on to a, or stops passing it on if a is nil
+func (m *Mixer) Analyse(a *Analyser) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.analyser = a
+}
+
+// Fill fills a buffer of the audio device with the next of the mix, and
+// passes it on to the recorder and analyser, if there are any
+func (m *Mixer) Fill(buf []byte) {
+	n := len(buf) / (m.stream.Bits / 8)
+	if cap(m.mix) < n {
+		m.mix = make([]float32, n)
+	}
+	m.Mix(m.mix[:n])
+	m.stream.Encode(m.mix[:n], buf)
+	m.mu.Lock()
+	r, a := m.recorder, m.analyser
+	m.mu.Unlock()
+	if r != nil {
+		r.Capture(m.mix[:n])
+	}
+	if a != nil {
+		a.Capture(m.mix[:n])
+	}
+}
+
+// Render mixes into w a buffer of the audio device at a time, as fast as it
+// can rather than as the device asks, until done is closed
+func (m *Mixer) Render(w *WavWriter, done <-chan struct{}) error {
+	buf := make([]float32, int(audioBufferFrames)*m.Channels)
+	for {
+		m.Mix(buf)
+		if err := w.WriteSamples(buf); err != nil {
+			return err
+		}
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+	}
+}
+
+// Mix fills out with the next samples of every source and voice, summed,
+// through the effects and limited, dropping the voices that have ended
+func (m *Mixer) Mix(out []float32) {
+	for i := range out {
+		out[i] = 0
+	}
+	m.mu.Lock()
+	sources := m.sources
+	m.mu.Unlock()
+	if cap(m.buf) < len(out) {
+		m.buf = make([]float32, len(out))
+	}
+	buf := m.buf[:len(out)]
+	for _, source := range sources {
+		source.Next(buf)
+		for i, s := range buf {
+			out[i] += s
+		}
+	}
+	m.mu.Lock()
+	playing := m.voices[:0]
+	for _, v := range m.voices {
+		if v.mix(out, m.Channels, m.Rate) {
+			playing = append(playing, v)
+		}
+	}
+	for i := len(playing); i < len(m.voices); i++ {
+		m.voices[i] = nil
+	}
+	m.voices = playing
+	m.mu.Unlock()
+	m.Effects.Process(out)
+	m.limit(out)
+}
+
+// limit turns the gain down at once wherever a frame would go over the
+// limiterCeiling, and back up over the limiterRelease
+func (m *Mixer) limit(out []float32) {
+	for f := 0; f < len(out); f += m.Channels {
+		frame := out[f : f+m.Channels]
+		peak := 0.0
+		for _, s := range frame {
+			peak = math.Max(peak, math.Abs(float64(s)))
+		}
+		if peak*m.gain > limiterCeiling {
+			m.gain = limiterCeiling / peak
+		}
+		if m.gain < 1 {
+			for c := range frame {
+				frame[c] = float32(float64(frame[c]) * m.gain)
+			}
+			m.gain += (1 - m.gain) * m.release
+		}
+	}
+}
+
+// mix adds the voice to out, of the given channels and rate, and is false
+// once it has ended; called with the mixer locked
+func (v *Voice) mix(out []float32, channels, rate int) bool {
+	frames := v.sound.Frames
+	if v.stopped || frames <= 0 {
+		return false
+	}
+	step := v.pitch * float64(v.sound.Rate) / float64(rate)
+	gainL := v.volume * math.Min(1, 1-v.pan)
+	gainR := v.volume * math.Min(1, 1+v.pan)
+	for f := 0; f < len(out); f += channels {
+		if v.pos >= float64(frames) {
+			if !v.loop {
+				return false
+			}
+			v.pos = math.Mod(v.pos, float64(frames))
+		}
+		l, r := v.frameAt(v.pos)
+		if channels == 1 {
+			out[f] += float32((l*gainL + r*gainR) / 2)
+		} else {
+			out[f] += float32(l * gainL)
+			out[f+1] += float32(r * gainR)
+		}
+		v.pos += step
+	}
+	return v.pos < float64(frames) || v.loop
+}
+
+// frameAt is the left and right of the sound at a fractional frame, between
+// the frames either side, or the start again if it loops
+func (v *Voice) frameAt(pos float64) (l, r float64) {
+	s, channels := v.sound.Samples, v.sound.Channels
+	i := int(pos)
+	j := i + 1
+	if j >= v.sound.Frames {
+		if !v.loop {
+			j = i
+		} else {
+			j = 0
+		}
+	}
+	t := pos - float64(i)
+	at := func(frame, c int) float64 {
+		return float64(s[frame*channels+minInt(c, channels-1)])
+	}
+	l = at(i, 0) + (at(j, 0)-at(i, 0))*t
+	r = at(i, 1) + (at(j, 1)-at(i, 1))*t
+	return
+}
+
+func (v *Voice) SetVolume(volume float64) {
+	v.mixer.mu.Lock()
+	defer v.mixer.mu.Unlock()
+	v.volume = volume
+}
+
+func (v *Voice) SetPan(pan float64) {
+	v.mixer.mu.Lock()
+	defer v.mixer.mu.Unlock()
+	v.pan = math.Max(-1, math.Min(pan, 1))
+}
+
+func (v *Voice) SetPitch(pitch float64) {
+	v.mixer.mu.Lock()
+	defer v.mixer.mu.Unlock()
+	v.pitch = math.Max(voiceMinPitch, pitch)
+}
+
+func (v *Voice) SetLoop(loop bool) {
+	v.mixer.mu.Lock()
+	defer v.mixer.mu.Unlock()
+	v.loop = loop
+}
+
+func (v *Voice) Stop() {
+	v.mixer.mu.Lock()
+	defer v.mixer.mu.Unlock()
+	v.stopped = true
+}
+
+// Playing is false once the voice has ended or been stopped
+func (v *Voice) Playing() bool {
+	v.mixer.mu.Lock()
+	defer v.mixer.mu.Unlock()
+	if v.stopped {
+		return false
+	}
+	for _, playing := range v.mixer.voices {
+		if playing == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// render the track through a mixer turning it down by 6dB, to floats
+func renderThroughGain(t *testing.T, track Track, channels, rate int) *Sound {
+	m := NewMixer(WavInfo{Format: WAVE_FORMAT_PCM, Bits: 16, Channels: channels, Rate: rate})
+	m.Add(track)
+	if err := m.Effects.Configure([]EffectSpec{{Kind: "gain", Params: map[string]float64{"db": -6.0206}}}); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	w, err := NewWavWriter(&out, WavInfo{Format: WAVE_FORMAT_IEEE_FLOAT, Bits: 32, Channels: channels, Rate: rate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	track.Play()
+	if err := m.Render(w, track.Done()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := ReadWAV(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRender(t *testing.T) {
+	channels, rate, frames := 2, 8000, 10000
+	left, right := sine(rate, frames, 440, 0.8), sine(rate, frames, 660, 0.4)
+	sound := &Sound{WavInfo: WavInfo{Channels: channels, Rate: rate, Frames: frames}}
+	for f := range left {
+		sound.Samples = append(sound.Samples, left[f], right[f])
+	}
+	played := renderThroughGain(t, NewPlayer(sound), channels, rate)
+	buffers := (frames + int(audioBufferFrames) - 1) / int(audioBufferFrames)
+	if played.Frames != buffers*int(audioBufferFrames) {
+		t.Fatalf("rendered %d frames, want %d buffers of %d", played.Frames, buffers, audioBufferFrames)
+	}
+	for i, s := range played.Samples {
+		want := 0.0
+		if i < len(sound.Samples) {
+			want = float64(sound.Samples[i]) / 2
+		}
+		if math.Abs(float64(s)-want) > 1e-4 {
+			t.Fatalf("sample %d is %v, want %v through the effects", i, s, want)
+		}
+	}
+
+	// streamed from disk, waiting on the decoder rather than underrunning
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	w, err := CreateWAV(path, WavInfo{Format: WAVE_FORMAT_IEEE_FLOAT, Bits: 32, Channels: channels, Rate: rate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteSamples(sound.Samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	streamer, err := OpenStream(path, channels, rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer streamer.Close()
+	streamer.WaitForDisk(true)
+	streamed := renderThroughGain(t, streamer, channels, rate)
+	if streamer.Underruns() != 0 || streamed.Frames != played.Frames {
+		t.Fatalf("streamed %d frames with %d underruns, want %d frames", streamed.Frames, streamer.Underruns(), played.Frames)
+	}
+	for i := range played.Samples {
+		if streamed.Samples[i] != played.Samples[i] {
+			t.Fatalf("streamed sample %d is %v, played %v", i, streamed.Samples[i], played.Samples[i])
+		}
+	}
+}
+
+func TestMixerVoices(t *testing.T) {
+	channels, rate := 2, 1000
+	m := NewMixer(WavInfo{Format: WAVE_FORMAT_IEEE_FLOAT, Bits: 32, Channels: channels, Rate: rate})
+	// a steady quarter, left only, once; and a ramp at double speed, looped,
+	// right and half as loud left
+	steady := &Sound{WavInfo: WavInfo{Channels: 1, Rate: rate, Frames: 100}}
+	for f := 0; f < steady.Frames; f++ {
+		steady.Samples = append(steady.Samples, 0.25)
+	}
+	ramp := &Sound{WavInfo: WavInfo{Channels: 1, Rate: rate, Frames: 10}}
+	for f := 0; f < ramp.Frames; f++ {
+		ramp.Samples = append(ramp.Samples, float32(f)/20)
+	}
+	m.Play(steady, 1, -1, 1, false)
+	looped := m.Play(ramp, 1, 0.5, 2, true)
+	out := make([]float32, 300*channels)
+	m.Mix(out)
+	for f := 0; f < len(out)/channels; f++ {
+		r := float64(ramp.Samples[2*f%ramp.Frames])
+		l := r / 2
+		if f < steady.Frames {
+			l += 0.25
+		}
+		if math.Abs(float64(out[f*2])-l) > 1e-6 || math.Abs(float64(out[f*2+1])-r) > 1e-6 {
+			t.Fatalf("frame %d is %v, %v; want %v, %v", f, out[f*2], out[f*2+1], l, r)
+		}
+	}
+	if m.Voices() != 1 || !looped.Playing() {
+		t.Fatalf("%d voices after the steady one ended, want the looped one", m.Voices())
+	}
+
+	// a voice too loud is held down to the ceiling, and let back up after
+	loud := m.Play(steady, 8, 0, 1, true)
+	m.Mix(out)
+	peak := 0.0
+	for _, s := range out {
+		peak = math.Max(peak, math.Abs(float64(s)))
+	}
+	if peak > limiterCeiling+1e-6 || peak < limiterCeiling-0.01 {
+		t.Errorf("peak through the limiter is %g, want %g", peak, limiterCeiling)
+	}
+	loud.Stop()
+	for i := 0; i < 10; i++ {
+		m.Mix(out)
+	}
+	// the last of 12 mixes
+	last := 12*len(out)/channels - 1
+	if r, want := out[len(out)-1], ramp.Samples[2*last%ramp.Frames]; math.Abs(float64(r-want)) > 1e-3 {
+		t.Errorf("the limiter hasn't let the mix back up, at %v for %v", r, want)
+	}
+
+	// played backwards or not at all, a voice still ends
+	looped.Stop()
+	m.Play(steady, 1, 0, -1, false)
+	m.Play(steady, 1, 0, 0, false).SetPitch(-2)
+	for i := 0; m.Voices() > 0; i++ {
+		if float64(i) > float64(steady.Frames)/voiceMinPitch/300+1 {
+			t.Fatalf("%d voices at no pitch are still playing", m.Voices())
+		}
+		m.Mix(out)
+	}
+}
@@ -0,0 +1,147 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+/* a sound is played by the
+██████╗ ██╗      █████╗ ██╗   ██╗███████╗██████╗
+██╔══██╗██║     ██╔══██╗╚██╗ ██╔╝██╔════╝██╔══██╗
+██████╔╝██║     ███████║ ╚████╔╝ █████╗  ██████╔╝
+██╔═══╝ ██║     ██╔══██║  ╚██╔╝  ██╔══╝  ██╔══██╗
+██║     ███████╗██║  ██║   ██║   ███████╗██║  ██║
+╚═╝     ╚══════╝╚═╝  ╚═╝   ╚═╝   ╚══════╝╚═╝  ╚═╝*/
+
+func NewPlayer(sound *Sound) *Player {
+	return &Player{
+		Channels: sound.Channels,
+		Rate:     sound.Rate,
+		samples:  sound.Samples,
+		ended:    make(chan struct{}),
+	}
+}
+
+// Track is a Source that can be paused and sought, like a Player or a Streamer
+type Track interface {
+	Source
+	Play()
+	Pause()
+	Stop()
+	Playing() bool
+	Seek(t time.Duration)
+	Position() time.Duration
+	Duration() time.Duration
+	Done() <-chan struct{}
+}
+
+// Player owns a sound and the position it is played from. The mixer calls
+// Next on SDL's audio thread, so every method takes the lock, except
+// those reading the samples, which never change
+type Player struct {
+	Channels int
+	Rate     int // frames per second
+	/* private */
+	mu      sync.Mutex
+	samples []float32
+	pos     int // of the next sample
+	playing bool
+	ended   chan struct{}
+	endOnce sync.Once
+}
+
+// Play from the position, or from the start if it had reached the end
+func (p *Player) Play() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pos >= len(p.samples) {
+		p.pos = 0
+	}
+	p.playing = true
+}
+
+func (p *Player) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.playing = false
+}
+
+// Stop pauses, and goes back to the start
+func (p *Player) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.playing = false
+	p.pos = 0
+}
+
+func (p *Player) Playing() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playing
+}
+
+// Seek to a time from the start, within the sound
+func (p *Player) Seek(t time.Duration) {
+	frame := int(math.Round(t.Seconds() * float64(p.Rate)))
+	frame = maxInt(0, minInt(frame, p.Frames()))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pos = frame * p.Channels
+}
+
+// Position is the time from the start of the next frame to be played
+func (p *Player) Position() time.Duration {
+	return p.frameTime(p.Frame())
+}
+
+// Frame is the next to be played
+func (p *Player) Frame() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pos / p.Channels
+}
+
+func (p *Player) Duration() time.Duration {
+	return p.frameTime(p.Frames())
+}
+
+func (p *Player) frameTime(frame int) time.Duration {
+	return time.Duration(frame) * time.Second / time.Duration(p.Rate)
+}
+
+// Frames is the number of samples of each channel
+func (p *Player) Frames() int {
+	return len(p.samples) / p.Channels
+}
+
+// Sample is the i-th sample, from -1 to 1, interleaved by channel
+func (p *Player) Sample(i int) float64 {
+	return float64(p.samples[i])
+}
+
+// Done is closed when playing first reaches the end
+func (p *Player) Done() <-chan struct{} {
+	return p.ended
+}
+
+// Next fills out with the next samples, or silence while paused or past the
+// end, and is how many of them were played
+func (p *Player) Next(out []float32) (n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.playing {
+		n = copy(out, p.samples[p.pos:])
+		p.pos += n
+		if p.pos >= len(p.samples) {
+			p.playing = false
+			p.endOnce.Do(func() { close(p.ended) })
+		}
+	}
+	for i := n; i < len(out); i++ {
+		out[i] = 0
+	}
+	return
+}
@@ -0,0 +1,66 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+)
+
+/* what we hear can be kept by the
+██████╗ ███████╗ ██████╗ ██████╗ ██████╗ ██████╗ ███████╗██████╗
+██╔══██╗██╔════╝██╔════╝██╔═══██╗██╔══██╗██╔══██╗██╔════╝██╔══██╗
+██████╔╝█████╗  ██║     ██║   ██║██████╔╝██║  ██║█████╗  ██████╔╝
+██╔══██╗██╔══╝  ██║     ██║   ██║██╔══██╗██║  ██║██╔══╝  ██╔══██╗
+██║  ██║███████╗╚██████╗╚██████╔╝██║  ██║██████╔╝███████╗██║  ██║
+╚═╝  ╚═╝╚══════╝ ╚═════╝ ╚═════╝ ╚═╝  ╚═╝╚═════╝ ╚══════╝╚═╝  ╚═╝*/
+
+// Recorder writes the output stream to a WAV file from its own goroutine,
+// so the audio callback never waits on the disk
+type Recorder struct {
+	*Capturer
+	/* private */
+	w    *WavWriter
+	done chan error
+}
+
+// StartRecorder creates a WAV file at path for a stream in the format of info
+func StartRecorder(path string, info WavInfo) (*Recorder, error) {
+	w, err := CreateWAV(path, info)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{
+		Capturer: NewCapturer(recordBuffers, int(audioBufferFrames)*info.Channels),
+		w:        w,
+		done:     make(chan error, 1),
+	}
+	go r.run()
+	return r, nil
+}
+
+// run encodes the samples as the device was sent them, so a recording at its
+// format is what was heard to the bit
+func (r *Recorder) run() {
+	var err error
+	r.each(func(samples []float32) {
+		if err == nil {
+			err = r.w.WriteSamples(samples)
+		}
+	})
+	if cerr := r.w.Close(); err == nil {
+		err = cerr
+	}
+	r.done <- err
+}
+
+// Close writes what's left and finishes the file; Capture must not be called after
+func (r *Recorder) Close() error {
+	r.end()
+	err := <-r.done
+	if dropped := r.Dropped(); dropped > 0 {
+		log.WithFields(log.Fields{
+			"dropped": dropped,
+		}).Warn("Recorder fell behind")
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	info := WavInfo{Format: WAVE_FORMAT_PCM, Channels: 2, Rate: 44100, Bits: 16}
+	path := filepath.Join(t.TempDir(), "take.wav")
+	r, err := StartRecorder(path, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := make([]float32, int(audioBufferFrames)*info.Channels)
+	captures := 0
+	if n := testing.AllocsPerRun(recordBuffers/2, func() {
+		for i := range samples {
+			samples[i] = float32(captures%100) / 128
+		}
+		r.Capture(samples)
+		captures++
+	}); n != 0 {
+		t.Errorf("capture allocates %v times", n)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (captures - r.Dropped()) * int(audioBufferFrames); s.Frames != want {
+		t.Fatalf("recorded %d frames, want %d", s.Frames, want)
+	}
+	if r.Dropped() == 0 {
+		for i, v := range s.Samples {
+			if want := float32(i/len(samples)%100) / 128; v != want {
+				t.Fatalf("sample %d is %v, want %v", i, v, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/* a tune is played on the synth by the
+███████╗███████╗ ██████╗ ██╗   ██╗███████╗███╗   ██╗ ██████╗███████╗██████╗
+██╔════╝██╔════╝██╔═══██╗██║   ██║██╔════╝████╗  ██║██╔════╝██╔════╝██╔══██╗
+███████╗█████╗  ██║   ██║██║   ██║█████╗  ██╔██╗ ██║██║     █████╗  ██████╔╝
+╚════██║██╔══╝  ██║▄▄ ██║██║   ██║██╔══╝  ██║╚██╗██║██║     ██╔══╝  ██╔══██╗
+███████║███████╗╚██████╔╝╚██████╔╝███████╗██║ ╚████║╚██████╗███████╗██║  ██║
+╚══════╝╚══════╝ ╚══▀▀═╝  ╚═════╝ ╚══════╝╚═╝  ╚═══╝ ╚═════╝╚══════╝╚═╝  ╚═╝*/
+
+var (
+	sequencerGate float64 = 0.9 // of its steps that a note is held, before its release
+	noteSemitones         = map[byte]int{'C': -9, 'D': -7, 'E': -5, 'F': -4, 'G': -2, 'A': 0, 'B': 2}
+)
+
+// Step of a sequence plays a note, rests, or holds the note before it
+type Step struct {
+	Freq float64 // Hz, or 0 to rest
+	Tie  bool    // holds the note before for another step
+}
+
+// ParseSteps reads a sequence of steps separated by spaces: a note named like
+// C4, F#3 or Bb2, or a frequency in Hz like 220; - to hold the note before
+// for another step, or . to rest
+func ParseSteps(pattern string) ([]Step, error) {
+	var steps []Step
+	for _, field := range strings.Fields(pattern) {
+		switch field {
+		case "-":
+			steps = append(steps, Step{Tie: true})
+		case ".":
+			steps = append(steps, Step{})
+		default:
+			freq, err := NoteFreq(field)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, Step{Freq: freq})
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no steps in %q", pattern)
+	}
+	return steps, nil
+}
+
+// NoteFreq is the frequency of a note named like C4, F#3 or Bb2, in equal
+// temperament where A4 is 440Hz, or of a number of Hz
+func NoteFreq(name string) (float64, error) {
+	if hz, err := strconv.ParseFloat(name, 64); err == nil && hz > 0 {
+		return hz, nil
+	}
+	if len(name) < 2 {
+		return 0, fmt.Errorf("not a note: %q", name)
+	}
+	semitone, ok := noteSemitones[strings.ToUpper(name)[0]]
+	if !ok {
+		return 0, fmt.Errorf("not a note: %q", name)
+	}
+	octave := name[1:]
+	switch name[1] {
+	case '#':
+		semitone, octave = semitone+1, name[2:]
+	case 'b':
+		semitone, octave = semitone-1, name[2:]
+	}
+	n, err := strconv.Atoi(octave)
+	if err != nil {
+		return 0, fmt.Errorf("not a note: %q", name)
+	}
+	return 440 * math.Pow(2, float64(semitone+12*(n-4))/12), nil
+}
+
+func NewSequencer(synth *Synth, steps []Step, stepSeconds float64) *Sequencer {
+	return &Sequencer{
+		Synth:      synth,
+		steps:      steps,
+		stepFrames: stepSeconds * float64(synth.Rate),
+		off:        math.Inf(1),
+	}
+}
+
+// Sequencer is a Source that plays its steps on a synth, one note at a time,
+// over and over. Notes start on the very frame they're due, not just at the
+// start of a buffer
+type Sequencer struct {
+	Synth *Synth
+	/* private */
+	mu         sync.Mutex
+	steps      []Step
+	stepFrames float64
+	step       int     // next to play
+	next       float64 // frames until it
+	off        float64 // frames until the note playing is released
+	note       *Note   // played again for each step, so the synth keeps no more
+}
+
+// SetSteps plays another sequence, from its start at the next step
+func (q *Sequencer) SetSteps(steps []Step) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.steps, q.step = steps, 0
+}
+
+// Next fills out with what the synth plays, and is how many samples were
+// played, rather than silence
+func (q *Sequencer) Next(out []float32) (n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	channels := q.Synth.Channels
+	for f, frames := 0, len(out)/channels; f < frames; {
+		for q.next <= 0 {
+			q.advance()
+		}
+		if q.off <= 0 {
+			q.note.Off()
+			q.off = math.Inf(1)
+		}
+		m := int(math.Ceil(math.Min(float64(frames-f), math.Min(q.next, q.off))))
+		n += q.Synth.Next(out[f*channels : (f+m)*channels])
+		f += m
+		q.next -= float64(m)
+		q.off -= float64(m)
+	}
+	return
+}
+
+// advance plays the next step; called locked
+func (q *Sequencer) advance() {
+	s := q.steps[q.step]
+	q.step = (q.step + 1) % len(q.steps)
+	q.next += q.stepFrames
+	if s.Freq <= 0 || s.Tie {
+		return
+	}
+	if q.note == nil {
+		q.note = q.Synth.NoteOn(s.Freq, 1)
+	} else {
+		q.note.Play(s.Freq, 1)
+	}
+	held := 1
+	for q.steps[(q.step+held-1)%len(q.steps)].Tie && held < len(q.steps) {
+		held++
+	}
+	q.off = (float64(held) - 1 + sequencerGate) * q.stepFrames
+}
@@ -0,0 +1,102 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNoteFreq(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		want float64 // or 0 for no note
+	}{
+		{"A4", 440},
+		{"a4", 440},
+		{"A3", 220},
+		{"Bb3", 233.0819},
+		{"A#3", 233.0819},
+		{"C4", 261.6256},
+		{"F#3", 184.9972},
+		{"B0", 30.8677},
+		{"220", 220},
+		{"27.5", 27.5},
+		{"", 0},
+		{"A", 0},
+		{"H4", 0},
+		{"Cx", 0},
+		{"Bb", 0},
+		{"#4", 0},
+		{"0", 0},
+		{"-3", 0},
+	} {
+		got, err := NoteFreq(c.name)
+		switch {
+		case c.want == 0 && err == nil:
+			t.Errorf("%q is %g Hz, want an error", c.name, got)
+		case c.want != 0 && err != nil:
+			t.Errorf("%q: %v", c.name, err)
+		case math.Abs(got-c.want) > 1e-4:
+			t.Errorf("%q is %g Hz, want %g", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParseSteps(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		want    []Step // or nil for an error
+	}{
+		{"A4 - . 220", []Step{{Freq: 440}, {Tie: true}, {}, {Freq: 220}}},
+		{"  A4\t.\n", []Step{{Freq: 440}, {}}},
+		{"-", []Step{{Tie: true}}},
+		{"", nil},
+		{"A4 H4", nil},
+		{"A4 --", nil},
+	} {
+		got, err := ParseSteps(c.pattern)
+		if c.want == nil {
+			if err == nil {
+				t.Errorf("%q is %v, want an error", c.pattern, got)
+			}
+		} else if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q is %v, %v; want %v", c.pattern, got, err, c.want)
+		}
+	}
+}
+
+func TestSequencer(t *testing.T) {
+	// steps of 7.25 frames, so they're due between frames, in buffers of 16
+	// frames, so they fall part way through
+	channels, rate, step := 2, 1000, 7.25
+	synth := NewSynth(channels, rate)
+	synth.Wave = WAVEFORM_TRIANGLE
+	synth.Envelope = Envelope{Sustain: 1}
+	steps, err := ParseSteps("A4 - .")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewSequencer(synth, steps, step/float64(rate))
+	out := make([]float32, 0, 200*channels)
+	buf := make([]float32, 16*channels)
+	for len(out) < cap(out) {
+		q.Next(buf)
+		out = append(out, buf...)
+	}
+	// each note starts on the first frame at or after it's due, and is
+	// held through the tie for sequencerGate of the next step
+	held := (1 + sequencerGate) * step
+	for f := 0; f < len(out)/channels; f++ {
+		due := math.Floor(float64(f)/(3*step)) * 3 * step
+		start := int(math.Ceil(due))
+		want := f >= start && f < start+int(math.Ceil(held))
+		if got := out[f*channels] != 0; got != want {
+			t.Fatalf("frame %d sounding is %v, want %v, for a note due at %g", f, got, want, due)
+		}
+		if out[f*channels+1] != out[f*channels] {
+			t.Fatalf("frame %d differs between channels", f)
+		}
+	}
+}
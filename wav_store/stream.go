@@ -0,0 +1,336 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+/* long files are played from disk by the
+███████╗████████╗██████╗ ███████╗ █████╗ ███╗   ███╗███████╗██████╗
+██╔════╝╚══██╔══╝██╔══██╗██╔════╝██╔══██╗████╗ ████║██╔════╝██╔══██╗
+███████╗   ██║   ██████╔╝█████╗  ███████║██╔████╔██║█████╗  ██████╔╝
+╚════██║   ██║   ██╔══██╗██╔══╝  ██╔══██║██║╚██╔╝██║██╔══╝  ██╔══██╗
+███████║   ██║   ██║  ██║███████╗██║  ██║██║ ╚═╝ ██║███████╗██║  ██║
+╚══════╝   ╚═╝   ╚═╝  ╚═╝╚══════╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝╚═╝  ╚═╝*/
+
+var (
+	streamBufferSeconds float64 = 2    // decoded ahead of the playhead
+	streamChunkFrames   int     = 4096 // decoded at a time
+)
+
+// OpenStream opens a sound file to be played a piece at a time, converted to
+// the given channels and rate
+func OpenStream(path string, channels, rate int) (*Streamer, error) {
+	src, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Streamer{
+		Channels: channels,
+		Rate:     rate,
+		conv:     newConverter(src, channels, rate),
+		ring:     make([]float32, int(streamBufferSeconds*float64(rate))*channels),
+		room:     make(chan struct{}, 1),
+		filled:   make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		decoded:  make(chan struct{}),
+		ended:    make(chan struct{}),
+	}
+	s.frames = s.conv.Frames()
+	return s, nil
+}
+
+// Streamer plays a file from disk with constant memory. Its own goroutine
+// decodes into a ring buffer ahead of the playhead, and the mixer's calls to
+// Next only ever copy out of it; if the disk falls behind, it plays silence
+type Streamer struct {
+	Channels int
+	Rate     int // frames per second
+	/* private */
+	conv      *converter // only touched by the decoder, once it has started
+	frames    int
+	mu        sync.Mutex
+	ring      []float32
+	head      int // of the next sample to be played in the ring
+	count     int // samples in the ring
+	pos       int // frame of the next sample to be played
+	playing   bool
+	eof       bool // the decoder has reached the end
+	seeks     int  // counted, so the decoder drops what it decoded before one
+	underruns int
+	wait      bool          // for the decoder, rather than play silence
+	room      chan struct{} // signalled whenever there's more space in the ring
+	filled    chan struct{} // signalled whenever the decoder has written to it
+	quit      chan struct{}
+	decoded   chan struct{} // closed when the decoder has finished
+	started   sync.Once
+	ended     chan struct{}
+	endOnce   sync.Once
+}
+
+// decode keeps the ring as full as it can, until Close
+func (s *Streamer) decode() {
+	defer close(s.decoded)
+	chunk := make([]float32, streamChunkFrames*s.Channels)
+	seeks := 0
+	for {
+		s.mu.Lock()
+		sought, frame := s.seeks != seeks, s.pos
+		seeks = s.seeks
+		full := s.eof || len(s.ring)-s.count < len(chunk)
+		s.mu.Unlock()
+		if sought {
+			if err := s.conv.SeekFrame(frame); err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Warn("Failed to seek")
+			}
+			continue
+		}
+		if full {
+			select {
+			case <-s.room:
+			case <-s.quit:
+				return
+			}
+			continue
+		}
+		n, err := s.conv.Read(chunk)
+		if err != nil && err != io.EOF {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn("Failed to read")
+		}
+		s.mu.Lock()
+		if s.seeks == seeks {
+			s.write(chunk[:n])
+			s.eof = err != nil
+		}
+		s.mu.Unlock()
+		select {
+		case s.filled <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// write samples to the end of the ring, which has room; called locked
+func (s *Streamer) write(in []float32) {
+	tail := (s.head + s.count) % len(s.ring)
+	n := copy(s.ring[tail:], in)
+	copy(s.ring, in[n:])
+	s.count += len(in)
+}
+
+// Next fills out with the next samples, or silence while paused, past the end
+// or waiting on the disk, unless told to WaitForDisk, and is how many of them
+// were played
+func (s *Streamer) Next(out []float32) (n int) {
+	s.mu.Lock()
+	for s.wait && s.playing && !s.eof && s.count < len(out) {
+		s.mu.Unlock()
+		<-s.filled
+		s.mu.Lock()
+	}
+	if s.playing {
+		n = minInt(len(out), s.count)
+		m := copy(out[:n], s.ring[s.head:])
+		copy(out[m:n], s.ring)
+		s.head = (s.head + n) % len(s.ring)
+		s.count -= n
+		s.pos += n / s.Channels
+		if n < len(out) {
+			if s.eof {
+				s.playing = false
+				s.endOnce.Do(func() { close(s.ended) })
+			} else {
+				s.underruns++
+			}
+		}
+	}
+	s.mu.Unlock()
+	for i := n; i < len(out); i++ {
+		out[i] = 0
+	}
+	if n > 0 {
+		select {
+		case s.room <- struct{}{}:
+		default:
+		}
+	}
+	return
+}
+
+// Play from the position, or from the start if it had reached the end
+func (s *Streamer) Play() {
+	s.started.Do(func() { go s.decode() })
+	s.mu.Lock()
+	atEnd := s.eof && s.count == 0
+	s.playing = true
+	s.mu.Unlock()
+	if atEnd {
+		s.seek(0)
+	}
+}
+
+func (s *Streamer) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.playing = false
+}
+
+// Stop pauses, and goes back to the start
+func (s *Streamer) Stop() {
+	s.Pause()
+	s.seek(0)
+}
+
+func (s *Streamer) Playing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.playing
+}
+
+// Seek to a time from the start, within the sound; it plays silence until
+// the decoder catches up
+func (s *Streamer) Seek(t time.Duration) {
+	s.seek(int(math.Round(t.Seconds() * float64(s.Rate))))
+}
+
+func (s *Streamer) seek(frame int) {
+	s.mu.Lock()
+	s.pos = maxInt(0, frame)
+	if s.frames >= 0 {
+		s.pos = minInt(s.pos, s.frames)
+	}
+	s.head, s.count = 0, 0
+	s.eof = false
+	s.seeks++
+	s.mu.Unlock()
+	select {
+	case s.room <- struct{}{}:
+	default:
+	}
+}
+
+// Position is the time from the start of the next frame to be played
+func (s *Streamer) Position() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Duration(s.pos) * time.Second / time.Duration(s.Rate)
+}
+
+// Duration is 0 if the file doesn't say
+func (s *Streamer) Duration() time.Duration {
+	if s.frames < 0 {
+		return 0
+	}
+	return time.Duration(s.frames) * time.Second / time.Duration(s.Rate)
+}
+
+// Underruns is how many times the disk fell behind
+func (s *Streamer) Underruns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.underruns
+}
+
+// Done is closed when playing first reaches the end
+func (s *Streamer) Done() <-chan struct{} {
+	return s.ended
+}
+
+// WaitForDisk makes Next wait on the decoder rather than play silence, to
+// render as fast as the file can be decoded; never while the audio device
+// is playing it
+func (s *Streamer) WaitForDisk(wait bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wait = wait
+}
+
+// Close stops the decoder, and the file
+func (s *Streamer) Close() error {
+	close(s.quit)
+	s.started.Do(func() { close(s.decoded) })
+	<-s.decoded
+	return s.conv.src.Close()
+}
+
+func newConverter(src Decoder, channels, rate int) *converter {
+	c := &converter{
+		src:      src,
+		info:     src.Info(),
+		channels: channels,
+		rate:     rate,
+		in:       make([]float32, streamChunkFrames*src.Info().Channels),
+	}
+	if c.info.Rate != rate {
+		c.resampler = NewResampler(channels, c.info.Rate, rate)
+	}
+	return c
+}
+
+// converter reads a sound file converted to other channels and rate
+type converter struct {
+	src       Decoder
+	info      *WavInfo // of the file
+	channels  int
+	rate      int
+	resampler *Resampler // if the rates differ
+	in        []float32  // decoded from the file
+	buf       []float32  // resampled
+	out       []float32  // converted, not yet read
+	eof       bool
+}
+
+// Frames is how many there will be, converted, or -1 if unknown
+func (c *converter) Frames() int {
+	if c.info.Frames < 0 {
+		return -1
+	}
+	return int((int64(c.info.Frames)*int64(c.rate) + int64(c.info.Rate) - 1) / int64(c.info.Rate))
+}
+
+// Read as many of the next samples as fit in out, or io.EOF after the last
+func (c *converter) Read(out []float32) (int, error) {
+	for len(c.out) == 0 {
+		if c.eof {
+			return 0, io.EOF
+		}
+		n, err := c.src.Read(c.in)
+		if err == io.EOF {
+			c.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+		c.out = Remix(c.in[:n], c.info.Channels, c.channels)
+		if c.resampler != nil {
+			c.buf = c.resampler.Process(c.out, c.buf[:0])
+			if c.eof {
+				c.buf = c.resampler.Flush(c.buf)
+			}
+			c.out = c.buf
+		}
+	}
+	n := copy(out, c.out)
+	c.out = c.out[n:]
+	return n, nil
+}
+
+// SeekFrame makes frame, at the converted rate, the next to be read
+func (c *converter) SeekFrame(frame int) error {
+	if err := c.src.SeekFrame(int(int64(frame) * int64(c.info.Rate) / int64(c.rate))); err != nil {
+		return err
+	}
+	if c.resampler != nil {
+		c.resampler.Reset()
+	}
+	c.out, c.eof = nil, false
+	return nil
+}
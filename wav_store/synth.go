@@ -0,0 +1,309 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+/* sound from nothing is made by the
+███████╗██╗   ██╗███╗   ██╗████████╗██╗  ██╗
+██╔════╝╚██╗ ██╔╝████╗  ██║╚══██╔══╝██║  ██║
+███████╗ ╚████╔╝ ██╔██╗ ██║   ██║   ███████║
+╚════██║  ╚██╔╝  ██║╚██╗██║   ██║   ██╔══██║
+███████║   ██║   ██║ ╚████║   ██║   ██║  ██║
+╚══════╝   ╚═╝   ╚═╝  ╚═══╝   ╚═╝   ╚═╝  ╚═╝*/
+
+var (
+	synthNotes  int     = 16   // room for, before the list of those playing grows
+	synthVolume float64 = 0.25 // of a note at full velocity
+)
+
+type Waveform uint
+
+const (
+	WAVEFORM_SINE Waveform = iota
+	WAVEFORM_SQUARE
+	WAVEFORM_SAW
+	WAVEFORM_TRIANGLE
+	WAVEFORM_NOISE
+	numWaveforms
+)
+
+func (w Waveform) Name() string {
+	switch w {
+	case WAVEFORM_SINE:
+		return "sine"
+	case WAVEFORM_SQUARE:
+		return "square"
+	case WAVEFORM_SAW:
+		return "saw"
+	case WAVEFORM_TRIANGLE:
+		return "triangle"
+	case WAVEFORM_NOISE:
+		return "noise"
+	}
+	return ""
+}
+
+func waveformNamed(name string) (Waveform, bool) {
+	for w := Waveform(0); w < numWaveforms; w++ {
+		if w.Name() == name {
+			return w, true
+		}
+	}
+	return WAVEFORM_SINE, false
+}
+
+// Oscillator makes a tone of a waveform at a frequency. The square and saw
+// have their edges smoothed by PolyBLEP, so high notes don't alias
+type Oscillator struct {
+	Wave Waveform
+	Freq float64 // Hz
+	/* private */
+	phase float64 // from 0 to 1 through the cycle
+	noise uint32  // state of the xorshift
+}
+
+// Sample is the next sample, from -1 to 1, at rate frames per second
+func (o *Oscillator) Sample(rate int) float64 {
+	dt := o.Freq / float64(rate)
+	p := o.phase
+	o.phase += dt
+	o.phase -= math.Floor(o.phase)
+	switch o.Wave {
+	case WAVEFORM_SQUARE:
+		s := 1.0
+		if p >= 0.5 {
+			s = -1
+		}
+		return s + polyBLEP(p, dt) - polyBLEP(math.Mod(p+0.5, 1), dt)
+	case WAVEFORM_SAW:
+		return 2*p - 1 - polyBLEP(p, dt)
+	case WAVEFORM_TRIANGLE:
+		return 1 - 4*math.Abs(p-0.5)
+	case WAVEFORM_NOISE:
+		if o.noise == 0 {
+			o.noise = 2463534242
+		}
+		o.noise ^= o.noise << 13
+		o.noise ^= o.noise >> 17
+		o.noise ^= o.noise << 5
+		return float64(int32(o.noise)) / (1 << 31)
+	}
+	return math.Sin(2 * math.Pi * p)
+}
+
+// polyBLEP is the correction to a step from 1 down to -1 at phase 0, for a
+// phase p that moves dt a sample
+func polyBLEP(p, dt float64) float64 {
+	switch {
+	case p < dt:
+		p /= dt
+		return p + p - p*p - 1
+	case p > 1-dt:
+		p = (p - 1) / dt
+		return p*p + p + p + 1
+	}
+	return 0
+}
+
+type envelopeStage uint
+
+const (
+	envelopeIdle envelopeStage = iota
+	envelopeAttack
+	envelopeDecay
+	envelopeSustain
+	envelopeRelease
+)
+
+// Envelope shapes the loudness of a note: it rises to full in Attack, falls
+// to Sustain in Decay, holds there until Off, then fades out in Release
+type Envelope struct {
+	Attack  float64 // seconds
+	Decay   float64 // seconds
+	Sustain float64 // level, from 0 to 1
+	Release float64 // seconds
+	/* private */
+	stage envelopeStage
+	level float64
+	fall  float64 // per second, while releasing
+}
+
+// On starts the attack, from wherever the level is
+func (e *Envelope) On() {
+	e.stage = envelopeAttack
+}
+
+// Off starts the release
+func (e *Envelope) Off() {
+	if e.stage == envelopeIdle {
+		return
+	}
+	e.stage = envelopeRelease
+	e.fall = e.level / e.Release
+}
+
+// Done is whether it has faded out after Off, or never started
+func (e *Envelope) Done() bool {
+	return e.stage == envelopeIdle
+}
+
+// Level is the next level, from 0 to 1, at rate frames per second
+func (e *Envelope) Level(rate int) float64 {
+	dt := 1 / float64(rate)
+	switch e.stage {
+	case envelopeAttack:
+		e.level += dt / e.Attack
+		if e.level >= 1 || e.Attack <= 0 {
+			e.level, e.stage = 1, envelopeDecay
+		}
+	case envelopeDecay:
+		e.level -= dt * (1 - e.Sustain) / e.Decay
+		if e.level <= e.Sustain || e.Decay <= 0 {
+			e.level, e.stage = e.Sustain, envelopeSustain
+		}
+	case envelopeRelease:
+		e.level -= dt * e.fall
+		if e.level <= 0 || e.Release <= 0 {
+			e.level, e.stage = 0, envelopeIdle
+		}
+	}
+	return e.level
+}
+
+func NewSynth(channels, rate int) *Synth {
+	return &Synth{
+		Channels: channels,
+		Rate:     rate,
+		Wave:     WAVEFORM_SINE,
+		Envelope: Envelope{Attack: 0.01, Decay: 0.1, Sustain: 0.7, Release: 0.2},
+		Volume:   synthVolume,
+		notes:    make([]*Note, 0, synthNotes),
+	}
+}
+
+// Synth is a Source that plays notes made up on the spot, the same in every
+// channel, for as long as any of them sound
+type Synth struct {
+	Channels int
+	Rate     int      // frames per second
+	Wave     Waveform // of notes started from now on
+	Envelope Envelope // of notes started from now on
+	Volume   float64  // of a note at full velocity
+	/* private */
+	mu    sync.Mutex
+	notes []*Note
+}
+
+// Note is one tone of a Synth, which can be changed while it plays, and
+// played again
+type Note struct {
+	/* private */
+	synth    *Synth
+	osc      Oscillator
+	env      Envelope
+	velocity float64 // from 0 to 1
+	sounding bool    // among the synth's notes
+}
+
+// NoteOn starts a new note at freq Hz, as loud as velocity, from 0 to 1; it
+// plays until Off, then for the synth's Release
+func (s *Synth) NoteOn(freq, velocity float64) *Note {
+	n := &Note{
+		synth: s,
+		osc:   Oscillator{Wave: s.Wave},
+		env:   s.Envelope,
+	}
+	n.Play(freq, velocity)
+	return n
+}
+
+// AllOff releases every note playing
+func (s *Synth) AllOff() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.notes {
+		n.env.Off()
+	}
+}
+
+// Next fills out with the sum of the notes playing, and is how many samples
+// were played, rather than silence
+func (s *Synth) Next(out []float32) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range out {
+		out[i] = 0
+	}
+	if len(s.notes) == 0 {
+		return 0
+	}
+	frames := len(out) / s.Channels
+	for _, n := range s.notes {
+		gain := n.velocity * s.Volume
+		for f := 0; f < frames; f++ {
+			v := float32(n.osc.Sample(s.Rate) * n.env.Level(s.Rate) * gain)
+			for c := f * s.Channels; c < (f+1)*s.Channels; c++ {
+				out[c] += v
+			}
+		}
+	}
+	// forget the notes that have faded out
+	kept := s.notes[:0]
+	for _, n := range s.notes {
+		if n.env.Done() {
+			n.sounding = false
+		} else {
+			kept = append(kept, n)
+		}
+	}
+	for i := len(kept); i < len(s.notes); i++ {
+		s.notes[i] = nil
+	}
+	s.notes = kept
+	return frames * s.Channels
+}
+
+// Play the note again at freq Hz, as loud as velocity; it rises from however
+// loud it still is, so there's no click
+func (n *Note) Play(freq, velocity float64) {
+	s := n.synth
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n.osc.Freq, n.velocity = freq, velocity
+	n.env.On()
+	if !n.sounding {
+		n.sounding = true
+		s.notes = append(s.notes, n)
+	}
+}
+
+// Off releases the note
+func (n *Note) Off() {
+	n.synth.mu.Lock()
+	defer n.synth.mu.Unlock()
+	n.env.Off()
+}
+
+// SetFreq glides the note to another frequency, e.g. to follow a value
+func (n *Note) SetFreq(freq float64) {
+	n.synth.mu.Lock()
+	defer n.synth.mu.Unlock()
+	n.osc.Freq = freq
+}
+
+func (n *Note) SetVelocity(velocity float64) {
+	n.synth.mu.Lock()
+	defer n.synth.mu.Unlock()
+	n.velocity = velocity
+}
+
+// Playing is whether the note can still be heard
+func (n *Note) Playing() bool {
+	n.synth.mu.Lock()
+	defer n.synth.mu.Unlock()
+	return n.sounding
+}
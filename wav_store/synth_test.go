@@ -0,0 +1,55 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	// 8 frames rising, 8 falling to half and 16 fading out, in steps that
+	// add up exactly
+	rate := 1024
+	e := Envelope{Attack: 8.0 / 1024, Decay: 8.0 / 1024, Sustain: 0.5, Release: 16.0 / 1024}
+	if !e.Done() {
+		t.Fatalf("an envelope never started isn't done")
+	}
+	e.On()
+	for f, want := range []float64{0.125, 0.25, 0.375, 0.5, 0.625, 0.75, 0.875, 1, 0.9375, 0.875, 0.8125, 0.75, 0.6875, 0.625, 0.5625, 0.5, 0.5, 0.5} {
+		if got := e.Level(rate); got != want {
+			t.Errorf("frame %d of attack and decay is %g, want %g", f, got, want)
+		}
+	}
+	if e.Done() {
+		t.Errorf("done while sustaining")
+	}
+	e.Off()
+	for f := 1; f < 16; f++ {
+		if got, want := e.Level(rate), 0.5-float64(f)/32; got != want {
+			t.Errorf("frame %d of release is %g, want %g", f, got, want)
+		}
+	}
+	if got := e.Level(rate); got != 0 || !e.Done() {
+		t.Errorf("after the release, level %g and done %v", got, e.Done())
+	}
+	// released part way up, it fades from there, taking as long
+	e.On()
+	e.Level(rate)
+	e.Off()
+	if got := e.Level(rate); got != 0.125-0.125/16 {
+		t.Errorf("released from 0.125 at %g, want %g", got, 0.125-0.125/16)
+	}
+	// stages of no length are skipped
+	e = Envelope{Sustain: 0.5}
+	e.On()
+	if got := e.Level(rate); got != 1 {
+		t.Errorf("no attack starts at %g, want 1", got)
+	}
+	if got := e.Level(rate); got != 0.5 {
+		t.Errorf("no decay falls to %g, want 0.5", got)
+	}
+	e.Off()
+	if got := e.Level(rate); got != 0 || !e.Done() {
+		t.Errorf("no release leaves level %g and done %v", got, e.Done())
+	}
+}
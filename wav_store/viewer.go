@@ -0,0 +1,442 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/charneykaye/go-SDL-experiements/plotview"
+	"github.com/veandco/go-sdl2/sdl"
+	"math"
+	"time"
+)
+
+/* watch the sound go by in the
+██╗   ██╗██╗███████╗██╗    ██╗███████╗██████╗
+██║   ██║██║██╔════╝██║    ██║██╔════╝██╔══██╗
+██║   ██║██║█████╗  ██║ █╗ ██║█████╗  ██████╔╝
+╚██╗ ██╔╝██║██╔══╝  ██║███╗██║██╔══╝  ██╔══██╗
+ ╚████╔╝ ██║███████╗╚███╔███╔╝███████╗██║  ██║
+  ╚═══╝  ╚═╝╚══════╝ ╚══╝╚══╝ ╚══════╝╚═╝  ╚═╝*/
+
+var (
+	viewerWidth          int32         = 1024
+	viewerWaveHeight     int32         = 400
+	viewerSpectrumHeight int32         = 200
+	viewerTicksX         int           = 10
+	viewerTicksY         int           = 4
+	viewZoomPerNotch     float64       = 1.2
+	viewMinSpan          float64       = 1e-4 // seconds in view, even of an empty sound
+	fftSize              int           = 2048
+	spectrumFloorDb      float64       = -96
+	waveColorPlayed      uint32        = 0xFFffb234
+	waveColorAhead       uint32        = 0xFF9c562f
+	spectrumColor        uint32        = 0xFFf4700b
+	playheadColor        uint32        = 0xFFFFFFFF
+	tickLabelBrightness  float64       = 0.9
+	viewerSeekStep       time.Duration = 5 * time.Second
+	fxVolume             float64       = 0.8
+)
+
+func NewViewer(player *Player, mixer *Mixer, fx *Sound) *Viewer {
+	v := &Viewer{
+		Name:     "wav_store",
+		Follow:   true,
+		player:   player,
+		mixer:    mixer,
+		fx:       fx,
+		freq:     float64(player.Rate),
+		channels: player.Channels,
+	}
+	v.Initialize()
+	return v
+}
+
+// Viewer shows the waveform of the samples, following the playhead, over the
+// spectrum of the latest fftSize of them
+type Viewer struct {
+	/* public */
+	Name     string
+	Wave     *plotview.Viewport // amplitude over seconds
+	Spectrum *plotview.Viewport // decibels over Hz
+	Follow   bool               // keep the playhead in view
+	/* private */
+	player   *Player
+	mixer    *Mixer
+	fx       *Sound // played over the top with keys 1 to 9
+	freq     float64
+	channels int
+	fft      *FFT
+	mono     []float64
+	db       []float64
+	dragging *plotview.Viewport
+	state    StateEnum
+	nowMs    uint32
+	lastMs   uint32
+	/* private: SDL */
+	sdlRenderer      *sdl.Renderer
+	sdlScreenSurface *sdl.Surface
+	sdlScreenTexture *sdl.Texture
+	sdlWindow        *sdl.Window
+}
+
+func (v *Viewer) Initialize() {
+	var err error
+
+	log.WithFields(log.Fields{
+		"name": v.Name,
+	}).Info("Initialize Viewer")
+
+	v.Wave = &plotview.Viewport{W: viewerWidth, H: viewerWaveHeight, YMin: -1, YMax: 1}
+	v.Spectrum = &plotview.Viewport{W: viewerWidth, H: viewerSpectrumHeight, Top: viewerWaveHeight, YMin: spectrumFloorDb, YMax: 0}
+	v.Reset()
+	v.fft = NewFFT(fftSize)
+	v.mono = make([]float64, fftSize)
+	v.db = make([]float64, fftSize/2+1)
+
+	v.sdlWindow, err = sdl.CreateWindow(
+		v.Name,
+		sdl.WINDOWPOS_UNDEFINED,
+		sdl.WINDOWPOS_UNDEFINED,
+		int(viewerWidth), int(viewerWaveHeight+viewerSpectrumHeight),
+		sdl.WINDOW_OPENGL,
+	)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("Failed to create window")
+	}
+
+	v.sdlRenderer, err = sdl.CreateRenderer(v.sdlWindow, -1,
+		sdl.RENDERER_ACCELERATED|sdl.RENDERER_PRESENTVSYNC)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("Failed to create renderer")
+	}
+
+	v.sdlScreenSurface, err = sdl.CreateRGBSurface(0, viewerWidth, viewerWaveHeight+viewerSpectrumHeight, int32(32), 0x00FF0000, 0x0000FF00, 0x000000FF, 0xFF000000)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("Failed to create screen surface")
+	}
+
+	v.ChangeState(STATE_LOADING)
+}
+
+// Reset shows the whole of the samples, and the whole of the spectrum
+func (v *Viewer) Reset() {
+	v.Wave.XMin, v.Wave.XMax = 0, math.Max(float64(v.player.Frames())/v.freq, viewMinSpan)
+	v.Spectrum.XMin, v.Spectrum.XMax = 0, v.freq/2
+}
+
+func (v *Viewer) Start() int {
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithFields(log.Fields{
+				"recover": r,
+			}).Warn("Viewer Recovered")
+		}
+		v.Teardown()
+	}()
+
+	v.ChangeState(STATE_PLAYING)
+	for v.Alive() {
+		if v.NowMs() {
+			v.PollEvents()
+			v.Render()
+		}
+	}
+	return 0
+}
+
+func (v *Viewer) Render() {
+	var err error
+
+	v.sdlScreenSurface.FillRect(nil, 0xFF000000)
+
+	playhead := float64(v.player.Frame()) / v.freq
+	if v.Follow && (playhead < v.Wave.XMin || playhead > v.Wave.XMax) {
+		// turn the page
+		span := v.Wave.XMax - v.Wave.XMin
+		v.Wave.XMin, v.Wave.XMax = playhead, playhead+span
+	}
+	v.RenderGuides(v.Wave)
+	v.RenderWaveform(playhead)
+	v.RenderTickLabels(v.Wave)
+	v.RenderGuides(v.Spectrum)
+	v.RenderSpectrum()
+	v.RenderTickLabels(v.Spectrum)
+	v.sdlScreenSurface.FillRect(&sdl.Rect{0, v.Spectrum.Top, viewerWidth, 1}, colorBrightness(0.5))
+
+	v.sdlScreenTexture, err = v.sdlRenderer.CreateTextureFromSurface(v.sdlScreenSurface)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("Could not create texture from surface")
+	}
+	defer v.sdlScreenTexture.Destroy()
+
+	v.sdlRenderer.Copy(v.sdlScreenTexture, nil, nil)
+
+	v.sdlRenderer.Present()
+}
+
+// RenderWaveform draws the least to the greatest sample under each column of
+// pixels, brighter where they have been played, and a line at the playhead
+func (v *Viewer) RenderWaveform(playhead float64) {
+	frames := v.player.Frames()
+	column := sdl.Rect{0, 0, 1, 1}
+	for px := int32(0); px < v.Wave.W; px++ {
+		from := int(math.Floor(v.Wave.FromScreenX(float64(px)) * v.freq))
+		to := int(math.Floor(v.Wave.FromScreenX(float64(px+1))*v.freq)) + 1 // joined to the next column
+		if to < 0 || from >= frames {
+			continue
+		}
+		from, to = maxInt(from, 0), minInt(to, frames-1)
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for f := from; f <= to; f++ {
+			for c := 0; c < v.channels; c++ {
+				s := v.player.Sample(f*v.channels + c)
+				lo, hi = math.Min(lo, s), math.Max(hi, s)
+			}
+		}
+		column.X = px
+		column.Y = int32(v.Wave.ToScreenY(hi))
+		column.H = int32(v.Wave.ToScreenY(lo)) - column.Y + 1
+		color := waveColorAhead
+		if float64(from)/v.freq < playhead {
+			color = waveColorPlayed
+		}
+		v.sdlScreenSurface.FillRect(&column, color)
+	}
+	if x := v.Wave.ToScreenX(playhead); x >= 0 && x < float64(v.Wave.W) {
+		v.sdlScreenSurface.FillRect(&sdl.Rect{int32(x), v.Wave.Top, 1, v.Wave.H}, playheadColor)
+	}
+}
+
+// RenderSpectrum draws the loudest frequency bin under each column of pixels,
+// of the fftSize frames up to the playhead, mixed to mono
+func (v *Viewer) RenderSpectrum() {
+	end := v.player.Frame()
+	for i := range v.mono {
+		v.mono[i] = 0
+		f := end - fftSize + i
+		if f < 0 || f >= v.player.Frames() {
+			continue
+		}
+		for c := 0; c < v.channels; c++ {
+			v.mono[i] += v.player.Sample(f*v.channels+c) / float64(v.channels)
+		}
+	}
+	v.fft.Decibels(v.mono, v.db)
+	binHz := v.freq / float64(fftSize)
+	bottom := v.Spectrum.Top + v.Spectrum.H
+	column := sdl.Rect{0, 0, 1, 1}
+	for px := int32(0); px < v.Spectrum.W; px++ {
+		from := int(math.Floor(v.Spectrum.FromScreenX(float64(px))/binHz + 0.5))
+		to := int(math.Floor(v.Spectrum.FromScreenX(float64(px+1))/binHz + 0.5))
+		if to < 0 || from >= len(v.db) {
+			continue
+		}
+		from, to = maxInt(from, 0), minInt(maxInt(to, from), len(v.db)-1)
+		loudest := spectrumFloorDb
+		for k := from; k <= to; k++ {
+			loudest = math.Max(loudest, v.db[k])
+		}
+		column.X = px
+		column.Y = int32(v.Spectrum.ToScreenY(loudest))
+		column.H = bottom - column.Y
+		v.sdlScreenSurface.FillRect(&column, spectrumColor)
+	}
+}
+
+// RenderGuides draws guides at round numbers across a viewport
+func (v *Viewer) RenderGuides(view *plotview.Viewport) {
+	stepX, ticksX := plotview.Ticks(view.XMin, view.XMax, viewerTicksX)
+	stepY, ticksY := plotview.Ticks(view.YMin, view.YMax, viewerTicksY)
+	for _, x := range ticksX {
+		v.sdlScreenSurface.FillRect(&sdl.Rect{int32(math.Floor(view.ToScreenX(x))), view.Top, 1, view.H}, colorBrightness(plotview.GuideBrightness(x, stepX)))
+	}
+	for _, y := range ticksY {
+		v.sdlScreenSurface.FillRect(&sdl.Rect{0, int32(math.Floor(view.ToScreenY(y))), view.W, 1}, colorBrightness(plotview.GuideBrightness(y, stepY)))
+	}
+}
+
+// RenderTickLabels labels the guides along the bottom and left of a viewport
+func (v *Viewer) RenderTickLabels(view *plotview.Viewport) {
+	stepX, ticksX := plotview.Ticks(view.XMin, view.XMax, viewerTicksX)
+	stepY, ticksY := plotview.Ticks(view.YMin, view.YMax, viewerTicksY)
+	color := colorBrightness(tickLabelBrightness)
+	for _, x := range ticksX {
+		plotview.RenderText(v.sdlScreenSurface, plotview.TickLabel(x, stepX), int32(math.Floor(view.ToScreenX(x)))+3, view.Top+view.H-plotview.FontHeight-2, 1, color)
+	}
+	for _, y := range ticksY {
+		if py := int32(math.Floor(view.ToScreenY(y))) + 2; py+plotview.FontHeight < view.Top+view.H-plotview.FontHeight {
+			plotview.RenderText(v.sdlScreenSurface, plotview.TickLabel(y, stepY), 3, py, 1, color)
+		}
+	}
+}
+
+func (v *Viewer) Stop() {
+	v.ChangeState(STATE_FINISHED)
+}
+
+func (v *Viewer) Teardown() {
+	log.Info("Teardown Viewer")
+	v.sdlRenderer.Destroy()
+	v.sdlWindow.Destroy()
+}
+
+func (v *Viewer) ChangeState(s StateEnum) {
+	v.state = s
+	log.WithFields(log.Fields{
+		"state": v.StateName(),
+	}).Info("Viewer changed")
+}
+
+func (v *Viewer) StateName() string {
+	switch v.state {
+	case STATE_LOADING:
+		return "Loading"
+	case STATE_PLAYING:
+		return "Playing"
+	case STATE_FINISHED:
+		return "Finished"
+	case STATE_FAILED:
+		return "Failed"
+	}
+	return ""
+}
+
+func (v *Viewer) PollEvents() {
+	var e sdl.Event
+	for e = sdl.PollEvent(); e != nil; e = sdl.PollEvent() {
+		switch t := e.(type) {
+		case *sdl.QuitEvent:
+			v.Stop()
+		case *sdl.KeyUpEvent:
+			if t.Keysym.Sym == sdl.K_ESCAPE {
+				v.Stop()
+			}
+		case *sdl.KeyDownEvent:
+			v.KeyDown(t.Keysym.Sym)
+		case *sdl.MouseButtonEvent:
+			v.dragging = nil
+			if t.Button == sdl.BUTTON_LEFT && t.State == sdl.PRESSED {
+				v.dragging = v.viewportAt(t.X, t.Y)
+			}
+		case *sdl.MouseMotionEvent:
+			if v.dragging != nil {
+				v.dragging.Pan(t.XRel, 0)
+				if v.dragging == v.Wave {
+					v.Follow = false
+				}
+			}
+		case *sdl.MouseWheelEvent:
+			mx, my, _ := sdl.GetMouseState()
+			if view := v.viewportAt(int32(mx), int32(my)); view != nil {
+				view.ZoomX(int32(mx), math.Pow(viewZoomPerNotch, float64(t.Y)))
+			}
+		}
+	}
+}
+
+func (v *Viewer) KeyDown(key sdl.Keycode) {
+	switch key {
+	case sdl.K_SPACE:
+		if v.player.Playing() {
+			v.player.Pause()
+		} else {
+			v.player.Play()
+		}
+	case sdl.K_LEFT:
+		v.player.Seek(v.player.Position() - viewerSeekStep)
+	case sdl.K_RIGHT:
+		v.player.Seek(v.player.Position() + viewerSeekStep)
+	case sdl.K_x:
+		v.mixer.StopAll()
+	case sdl.K_e:
+		v.mixer.Effects.Bypass(!v.mixer.Effects.Bypassed())
+	case sdl.K_r:
+		ReloadEffects(v.mixer.Effects)
+	case sdl.K_f:
+		v.Follow = true
+	case sdl.K_0:
+		v.Reset()
+	}
+	if key >= sdl.K_1 && key <= sdl.K_9 && v.fx != nil {
+		// from the left a semitone lower, to the right a semitone higher
+		k := float64(key - sdl.K_5)
+		v.mixer.Play(v.fx, fxVolume, k/4, math.Pow(2, k/12), fxLoop)
+	}
+}
+
+func (v *Viewer) viewportAt(x, y int32) *plotview.Viewport {
+	for _, view := range []*plotview.Viewport{v.Wave, v.Spectrum} {
+		if view.Contains(x, y) {
+			return view
+		}
+	}
+	return nil
+}
+
+func (v *Viewer) Alive() bool {
+	return v.state < STATE_FINISHED
+}
+
+func (v *Viewer) NowMs() bool {
+	v.nowMs = sdl.GetTicks()
+	if v.nowMs != v.lastMs {
+		v.lastMs = v.nowMs
+		return true
+	}
+	return false
+}
+
+type StateEnum uint
+
+const (
+	STATE_LOADING StateEnum = 3
+	STATE_PLAYING StateEnum = 5
+	// it can be assumed that all alive states are < STATE_FINISHED
+	STATE_FINISHED StateEnum = 6
+	STATE_FAILED   StateEnum = 7
+)
+
+var palette = []uint32{
+	0xFF000000,
+	0xFF251d1a,
+	0xFF3b2d23,
+	0xFF5a372d,
+	0xFF72432e,
+	0xFF9c562f,
+	0xFFbc5b26,
+	0xFFe16205,
+	0xFFf4700b,
+	0xFFfc8409,
+	0xFFff9315,
+	0xFFffb234,
+	0xFFffe14f,
+	0xFFffff53,
+	0xFFfffeab,
+	0xFFe16205,
+}
+
+func colorBrightness(b float64) uint32 {
+	return palette[int(b*float64(15))]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
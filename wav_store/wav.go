@@ -0,0 +1,566 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+/* samples are stored in a
+██╗    ██╗ █████╗ ██╗   ██╗███████╗
+██║    ██║██╔══██╗██║   ██║██╔════╝
+██║ █╗ ██║███████║██║   ██║█████╗
+██║███╗██║██╔══██║╚██╗ ██╔╝██╔══╝
+╚███╔███╔╝██║  ██║ ╚████╔╝ ███████╗
+ ╚══╝╚══╝ ╚═╝  ╚═╝  ╚═══╝  ╚══════╝*/
+
+// WavFormat is the format tag of the fmt chunk
+type WavFormat uint16
+
+const (
+	WAVE_FORMAT_PCM        WavFormat = 0x0001
+	WAVE_FORMAT_IEEE_FLOAT WavFormat = 0x0003
+	WAVE_FORMAT_EXTENSIBLE WavFormat = 0xFFFE
+)
+
+// WavInfo describes the samples of a WAV file
+type WavInfo struct {
+	Format      WavFormat // PCM or IEEE_FLOAT, even if the file said EXTENSIBLE
+	Extensible  bool
+	Channels    int
+	Rate        int // frames per second
+	Bits        int // per sample, as stored
+	ValidBits   int // of those, that are significant
+	ChannelMask uint32
+	Frames      int               // or -1 if the data runs to the end of the file
+	Tags        map[string]string // from the LIST INFO chunk, e.g. INAM is the title
+}
+
+// Sound is a whole decoded WAV file, its samples from -1 to 1, interleaved by channel
+type Sound struct {
+	WavInfo
+	Samples []float32
+}
+
+var ErrNotWAV = errors.New("not a RIFF WAVE file")
+
+// LoadWAV reads and decodes the whole of a WAV file
+func LoadWAV(path string) (*Sound, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadWAV(bufio.NewReader(f))
+}
+
+// ReadWAV decodes the samples of a WAV file; a data chunk cut short, as by
+// a recording that was never closed, is read as far as it goes. Tags in a
+// LIST chunk after the data are read too
+func ReadWAV(r io.Reader) (*Sound, error) {
+	info, data, err := ReadWavHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	// not allocated up front, so a header can't claim more than the file has
+	raw, err := io.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+	if info.Frames >= 0 {
+		if len(raw)%2 == 1 {
+			io.CopyN(io.Discard, r, 1)
+		}
+		readTrailingTags(r, info.Tags)
+	}
+	raw = raw[:len(raw)/info.FrameSize()*info.FrameSize()]
+	s := &Sound{WavInfo: *info, Samples: make([]float32, len(raw)/(info.Bits/8))}
+	s.Frames = len(raw) / info.FrameSize()
+	info.Decode(raw, s.Samples)
+	return s, nil
+}
+
+// WavReader decodes a WAV file a piece at a time, for files too long to load
+type WavReader struct {
+	WavInfo
+	/* private */
+	file  *os.File
+	start int64 // of the samples in the data chunk
+	frame int   // next to be read
+	raw   []byte
+}
+
+// OpenWAV reads the header of a WAV file, ready to read its samples
+func OpenWAV(path string) (*WavReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := newWavReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func newWavReader(f *os.File) (*WavReader, error) {
+	// read unbuffered, so the file is left at the start of the samples
+	info, _, err := ReadWavHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// a data chunk cut short, or of unknown size, runs to the end of the file
+	if frames := int((stat.Size() - start) / int64(info.FrameSize())); info.Frames < 0 || info.Frames > frames {
+		info.Frames = frames
+	}
+	return &WavReader{WavInfo: *info, file: f, start: start}, nil
+}
+
+func (w *WavReader) Info() *WavInfo {
+	return &w.WavInfo
+}
+
+// Read decodes as many of the next frames as fit in out, and is the number
+// of samples read, or io.EOF after the last of them
+func (w *WavReader) Read(out []float32) (int, error) {
+	frames := minInt(len(out)/w.Channels, w.Frames-w.frame)
+	if frames <= 0 {
+		return 0, io.EOF
+	}
+	size := frames * w.FrameSize()
+	if cap(w.raw) < size {
+		w.raw = make([]byte, size)
+	}
+	n, err := io.ReadFull(w.file, w.raw[:size])
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		// the file is shorter than it was
+		w.Frames = w.frame + n/w.FrameSize()
+		err = nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	frames = n / w.FrameSize()
+	if frames == 0 {
+		return 0, io.EOF
+	}
+	w.frame += frames
+	w.Decode(w.raw[:frames*w.FrameSize()], out[:frames*w.Channels])
+	return frames * w.Channels, nil
+}
+
+// SeekFrame makes frame the next to be read
+func (w *WavReader) SeekFrame(frame int) error {
+	frame = maxInt(0, minInt(frame, w.Frames))
+	if _, err := w.file.Seek(w.start+int64(frame*w.FrameSize()), io.SeekStart); err != nil {
+		return err
+	}
+	w.frame = frame
+	return nil
+}
+
+func (w *WavReader) Close() error {
+	return w.file.Close()
+}
+
+// ReadWavHeader reads the chunks of a WAV file up to its samples, and returns
+// a reader of the bytes of the data chunk; a LIST chunk after the data is not seen
+func ReadWavHeader(r io.Reader) (*WavInfo, io.Reader, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, nil, ErrNotWAV
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, nil, ErrNotWAV
+	}
+	var info *WavInfo
+	tags := map[string]string{}
+	for {
+		var head [8]byte
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return nil, nil, errors.New("no data chunk")
+		}
+		id, size := string(head[0:4]), binary.LittleEndian.Uint32(head[4:8])
+		switch id {
+		case "data":
+			if info == nil {
+				return nil, nil, errors.New("data chunk before fmt chunk")
+			}
+			info.Tags = tags
+			// a streaming writer that never patched the size leaves it 0 or ~0
+			if size == 0 || size == math.MaxUint32 {
+				info.Frames = -1
+				return info, r, nil
+			}
+			info.Frames = int(size) / info.FrameSize()
+			return info, io.LimitReader(r, int64(size)), nil
+		case "fmt ":
+			if size > wavMaxChunk {
+				return nil, nil, fmt.Errorf("fmt chunk of %d bytes", size)
+			}
+			chunk, err := readChunk(r, size)
+			if err != nil {
+				return nil, nil, fmt.Errorf("fmt chunk: %v", err)
+			}
+			if info, err = parseFmt(chunk); err != nil {
+				return nil, nil, err
+			}
+		case "LIST":
+			if size > wavMaxChunk {
+				if err := skipChunk(r, size); err != nil {
+					return nil, nil, fmt.Errorf("LIST chunk: %v", err)
+				}
+				continue
+			}
+			chunk, err := readChunk(r, size)
+			if err != nil {
+				return nil, nil, fmt.Errorf("LIST chunk: %v", err)
+			}
+			readInfoTags(chunk, tags)
+		default:
+			if err := skipChunk(r, size); err != nil {
+				return nil, nil, fmt.Errorf("%q chunk: %v", id, err)
+			}
+		}
+	}
+}
+
+// the most of a fmt or LIST chunk that's read into memory; a LIST chunk
+// longer than this is skipped
+const wavMaxChunk = 1 << 20
+
+// readChunk reads the size bytes of a chunk, and the byte padding it to an
+// even size
+func readChunk(r io.Reader, size uint32) ([]byte, error) {
+	chunk := make([]byte, int64(size)+int64(size%2))
+	if _, err := io.ReadFull(r, chunk); err != nil {
+		return nil, err
+	}
+	return chunk[:size], nil
+}
+
+func skipChunk(r io.Reader, size uint32) error {
+	_, err := io.CopyN(io.Discard, r, int64(size)+int64(size%2))
+	return err
+}
+
+// readTrailingTags reads the tags of any LIST INFO chunk after the data chunk,
+// stopping quietly at whatever it can't read
+func readTrailingTags(r io.Reader, tags map[string]string) {
+	for {
+		var head [8]byte
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return
+		}
+		id, size := string(head[0:4]), binary.LittleEndian.Uint32(head[4:8])
+		if id != "LIST" || size > wavMaxChunk {
+			if skipChunk(r, size) != nil {
+				return
+			}
+			continue
+		}
+		// the last chunk of a file may not be padded
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return
+		}
+		readInfoTags(chunk, tags)
+		if size%2 == 1 {
+			io.CopyN(io.Discard, r, 1)
+		}
+	}
+}
+
+// subFormat GUIDs of WAVE_FORMAT_EXTENSIBLE end in these 14 bytes, after the format tag
+const wavGUIDSuffix = "\x00\x00\x00\x00\x10\x00\x80\x00\x00\xAA\x00\x38\x9B\x71"
+
+func parseFmt(b []byte) (*WavInfo, error) {
+	if len(b) < 16 {
+		return nil, errors.New("fmt chunk too short")
+	}
+	info := &WavInfo{
+		Format:   WavFormat(binary.LittleEndian.Uint16(b[0:2])),
+		Channels: int(binary.LittleEndian.Uint16(b[2:4])),
+		Rate:     int(binary.LittleEndian.Uint32(b[4:8])),
+		Bits:     int(binary.LittleEndian.Uint16(b[14:16])),
+	}
+	info.ValidBits = info.Bits
+	if info.Format == WAVE_FORMAT_EXTENSIBLE {
+		if len(b) < 40 || string(b[26:40]) != wavGUIDSuffix {
+			return nil, errors.New("unsupported extensible format")
+		}
+		info.Extensible = true
+		info.ValidBits = int(binary.LittleEndian.Uint16(b[18:20]))
+		info.ChannelMask = binary.LittleEndian.Uint32(b[20:24])
+		info.Format = WavFormat(binary.LittleEndian.Uint16(b[24:26]))
+		if info.ValidBits == 0 {
+			info.ValidBits = info.Bits
+		}
+	}
+	switch {
+	case info.Channels < 1:
+		return nil, errors.New("no channels")
+	case info.Rate < 1:
+		return nil, errors.New("no sample rate")
+	case info.Format == WAVE_FORMAT_PCM && (info.Bits == 8 || info.Bits == 16 || info.Bits == 24 || info.Bits == 32):
+	case info.Format == WAVE_FORMAT_IEEE_FLOAT && (info.Bits == 32 || info.Bits == 64):
+	default:
+		return nil, fmt.Errorf("unsupported format %#x with %d bits", uint16(info.Format), info.Bits)
+	}
+	return info, nil
+}
+
+// readInfoTags reads the strings of a LIST INFO chunk, e.g. INAM, IART
+func readInfoTags(b []byte, tags map[string]string) {
+	if len(b) < 4 || string(b[0:4]) != "INFO" {
+		return
+	}
+	for b = b[4:]; len(b) >= 8; {
+		id, size := string(b[0:4]), binary.LittleEndian.Uint32(b[4:8])
+		if int64(size) > int64(len(b)-8) {
+			return
+		}
+		tags[id] = strings.TrimRight(string(b[8:8+size]), "\x00")
+		b = b[minInt(8+int(size+size%2), len(b)):]
+	}
+}
+
+// FrameSize is the bytes of one sample of every channel
+func (w *WavInfo) FrameSize() int {
+	return w.Channels * w.Bits / 8
+}
+
+// Decode converts little-endian samples in the stored format to -1..1;
+// out must hold len(raw) / (Bits / 8) samples
+func (w *WavInfo) Decode(raw []byte, out []float32) {
+	switch {
+	case w.Format == WAVE_FORMAT_IEEE_FLOAT && w.Bits == 32:
+		for i := range out {
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+		}
+	case w.Format == WAVE_FORMAT_IEEE_FLOAT:
+		for i := range out {
+			out[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(raw[i*8:])))
+		}
+	case w.Bits == 8: // unsigned
+		for i := range out {
+			out[i] = float32(int(raw[i])-128) / 128
+		}
+	case w.Bits == 16:
+		for i := range out {
+			out[i] = float32(int16(binary.LittleEndian.Uint16(raw[i*2:]))) / (1 << 15)
+		}
+	case w.Bits == 24:
+		for i := range out {
+			b := raw[i*3:]
+			out[i] = float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		}
+	case w.Bits == 32:
+		for i := range out {
+			out[i] = float32(float64(int32(binary.LittleEndian.Uint32(raw[i*4:]))) / (1 << 31))
+		}
+	}
+}
+
+// Encode converts samples from -1..1 to little-endian samples in the stored
+// format, clipping any beyond; raw must hold len(in) * Bits / 8 bytes
+func (w *WavInfo) Encode(in []float32, raw []byte) {
+	switch {
+	case w.Format == WAVE_FORMAT_IEEE_FLOAT && w.Bits == 32:
+		for i, s := range in {
+			binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(s))
+		}
+	case w.Format == WAVE_FORMAT_IEEE_FLOAT:
+		for i, s := range in {
+			binary.LittleEndian.PutUint64(raw[i*8:], math.Float64bits(float64(s)))
+		}
+	case w.Bits == 8:
+		for i, s := range in {
+			raw[i] = uint8(quantize(s, 7) + 128)
+		}
+	case w.Bits == 16:
+		for i, s := range in {
+			binary.LittleEndian.PutUint16(raw[i*2:], uint16(quantize(s, 15)))
+		}
+	case w.Bits == 24:
+		for i, s := range in {
+			v := quantize(s, 23)
+			raw[i*3], raw[i*3+1], raw[i*3+2] = uint8(v), uint8(v>>8), uint8(v>>16)
+		}
+	case w.Bits == 32:
+		for i, s := range in {
+			binary.LittleEndian.PutUint32(raw[i*4:], uint32(quantize(s, 31)))
+		}
+	}
+}
+
+// quantize scales s by 2^bits, the inverse of Decode, clipped to the integers that fit
+func quantize(s float32, bits uint) int32 {
+	v := math.Floor(float64(s)*float64(int64(1)<<bits) + 0.5)
+	return int32(math.Max(-float64(int64(1)<<bits), math.Min(float64(int64(1)<<bits-1), v)))
+}
+
+// WavWriter encodes samples to a WAV file as they come. The sizes in the
+// header are patched by Close if the file can seek, or else left ~0, which
+// ReadWAV takes to mean the data runs to the end of the file
+type WavWriter struct {
+	WavInfo
+	/* private */
+	w     io.Writer
+	file  *os.File // to close, if it was created by CreateWAV
+	bytes int64    // of samples written
+	raw   []byte
+}
+
+// CreateWAV creates the file at path to write samples to
+func CreateWAV(path string, info WavInfo) (*WavWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWavWriter(f, info)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.file = f
+	return w, nil
+}
+
+// NewWavWriter writes the header for samples in the format of info; files of
+// more than 2 channels or 16 bits are written as WAVE_FORMAT_EXTENSIBLE
+func NewWavWriter(out io.Writer, info WavInfo) (*WavWriter, error) {
+	w := &WavWriter{WavInfo: info, w: out}
+	if w.ValidBits == 0 {
+		w.ValidBits = w.Bits
+	}
+	if w.Channels > 2 || w.Bits > 16 {
+		w.Extensible = true
+	}
+	if w.Channels < 1 || w.Channels > math.MaxUint16 {
+		return nil, fmt.Errorf("%d channels", w.Channels)
+	}
+	if _, err := parseFmt(w.fmtChunk()); err != nil {
+		return nil, err
+	}
+	// more channels than there are usual speakers for are left unassigned
+	if w.Extensible && w.ChannelMask == 0 && w.Channels <= len(wavChannelMasks) {
+		w.ChannelMask = wavChannelMasks[w.Channels-1]
+	}
+	if err := w.writeHeader(math.MaxUint32); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// the usual speakers of 1 to 8 channels
+var wavChannelMasks = []uint32{0x4, 0x3, 0x7, 0x33, 0x37, 0x3F, 0x13F, 0x63F}
+
+func (w *WavWriter) fmtChunk() []byte {
+	b := make([]byte, 16, 40)
+	tag := w.Format
+	if w.Extensible {
+		tag = WAVE_FORMAT_EXTENSIBLE
+	}
+	binary.LittleEndian.PutUint16(b[0:], uint16(tag))
+	binary.LittleEndian.PutUint16(b[2:], uint16(w.Channels))
+	binary.LittleEndian.PutUint32(b[4:], uint32(w.Rate))
+	binary.LittleEndian.PutUint32(b[8:], uint32(w.Rate*w.FrameSize()))
+	binary.LittleEndian.PutUint16(b[12:], uint16(w.FrameSize()))
+	binary.LittleEndian.PutUint16(b[14:], uint16(w.Bits))
+	if w.Extensible {
+		b = b[:40]
+		binary.LittleEndian.PutUint16(b[16:], 22)
+		binary.LittleEndian.PutUint16(b[18:], uint16(w.ValidBits))
+		binary.LittleEndian.PutUint32(b[20:], w.ChannelMask)
+		binary.LittleEndian.PutUint16(b[24:], uint16(w.Format))
+		copy(b[26:], wavGUIDSuffix)
+	}
+	return b
+}
+
+// writeHeader writes RIFF, fmt and the head of the data chunk, of dataSize bytes
+func (w *WavWriter) writeHeader(dataSize uint32) error {
+	fmtChunk := w.fmtChunk()
+	riffSize := uint32(math.MaxUint32)
+	if dataSize != math.MaxUint32 {
+		riffSize = 4 + 8 + uint32(len(fmtChunk)) + 8 + dataSize + dataSize%2
+	}
+	head := make([]byte, 0, 20+len(fmtChunk)+8)
+	head = append(head, "RIFF"...)
+	head = binary.LittleEndian.AppendUint32(head, riffSize)
+	head = append(head, "WAVEfmt "...)
+	head = binary.LittleEndian.AppendUint32(head, uint32(len(fmtChunk)))
+	head = append(head, fmtChunk...)
+	head = append(head, "data"...)
+	head = binary.LittleEndian.AppendUint32(head, dataSize)
+	_, err := w.w.Write(head)
+	return err
+}
+
+// Write writes samples already encoded in the format of the file, which
+// must be whole frames
+func (w *WavWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// WriteSamples encodes and writes samples from -1..1, interleaved by channel
+func (w *WavWriter) WriteSamples(s []float32) error {
+	size := len(s) * w.Bits / 8
+	if cap(w.raw) < size {
+		w.raw = make([]byte, size)
+	}
+	w.Encode(s, w.raw[:size])
+	_, err := w.Write(w.raw[:size])
+	return err
+}
+
+// Close pads the data chunk to an even size, patches the sizes in the header
+// if it can seek back to them, and closes the file if CreateWAV created it
+func (w *WavWriter) Close() error {
+	err := w.finish()
+	if w.file != nil {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (w *WavWriter) finish() error {
+	if w.bytes%2 == 1 {
+		if _, err := w.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	seeker, ok := w.w.(io.WriteSeeker)
+	if !ok || w.bytes >= math.MaxUint32 {
+		return nil
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return nil // e.g. a pipe
+	}
+	if err := w.writeHeader(uint32(w.bytes)); err != nil {
+		return err
+	}
+	_, err := seeker.Seek(0, io.SeekEnd)
+	return err
+}
@@ -6,98 +6,328 @@ package main
 // void AudioCallback(void *userdata, Uint8 *stream, int len);
 import "C"
 import (
-	"encoding/binary"
+	"flag"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/veandco/go-sdl2/sdl"
+	"os"
+	"os/signal"
 	"reflect"
-	"time"
+	"runtime"
 	"runtime/debug"
+	"syscall"
 	"unsafe"
 )
 
 var (
-	sampleFile string = "song.wav"
-	samples   []uint16
-	nowSample int
+	sampleFile        string = "song.wav"
+	viewerOn          bool   = true
+	audioBufferFrames uint16 = 4096
+	deviceRate        int    = 44100
+	deviceChannels    int    = 2
+	deviceFormat      string = "s16"
+	recordFile        string
+	recordBuffers     int = 64
+	renderFile        string
+	renderBits        int = 16
+	fxFile            string
+	fxLoop            bool
+	streamOn          bool
+	effectsFile       string
+	synthSteps        string
+	synthWave         string  = "saw"
+	synthTempo        float64 = 120 // beats per minute, of four steps each
+	synthADSR         string  = "0.01,0.1,0.7,0.2"
+	analysisOutputs   string
+	mixer             *Mixer
 )
 
-func LoadSample(file string) *sdl.AudioSpec {
-	data, spec := sdl.LoadWAV(file, &sdl.AudioSpec{})
-	for n := 0; n < len(data); n += 2 {
-		samples = append(samples, binary.BigEndian.Uint16(data[n:n+2]))
+// LoadSound decodes the whole of a sound file, converted to the channels and
+// rate of the audio device
+func LoadSound(file string) (*Sound, error) {
+	sound, err := Load(file)
+	if err != nil {
+		return nil, err
 	}
-	return spec
+	log.WithFields(log.Fields{
+		"file":     file,
+		"channels": sound.Channels,
+		"rate":     sound.Rate,
+		"bits":     sound.Bits,
+		"frames":   sound.Frames,
+		"tags":     sound.Tags,
+	}).Info("Decoded")
+	if sound.Channels != deviceChannels || sound.Rate != deviceRate {
+		sound = sound.Convert(deviceChannels, deviceRate)
+		log.WithFields(log.Fields{
+			"file":     file,
+			"channels": sound.Channels,
+			"rate":     sound.Rate,
+		}).Info("Converted")
+	}
+	return sound, nil
+}
+
+// ReloadEffects configures the chain from the effects file again, keeping
+// it as it was if the file is wrong
+func ReloadEffects(chain *Chain) {
+	if effectsFile == "" {
+		return
+	}
+	specs, err := LoadEffects(effectsFile)
+	if err == nil {
+		err = chain.Configure(specs)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"file":  effectsFile,
+			"error": err,
+		}).Warn("Failed to load effects")
+		return
+	}
+	log.WithFields(log.Fields{
+		"file":    effectsFile,
+		"effects": len(specs),
+	}).Info("Loaded effects")
 }
 
-func NextSample() (s uint16) {
-	if nowSample < len(samples) {
-		s = samples[nowSample]
+// NewSynthSequencer plays the synth steps at the synth tempo, with the synth
+// waveform and envelope
+func NewSynthSequencer() (*Sequencer, error) {
+	steps, err := ParseSteps(synthSteps)
+	if err != nil {
+		return nil, err
+	}
+	synth := NewSynth(deviceChannels, deviceRate)
+	var ok bool
+	if synth.Wave, ok = waveformNamed(synthWave); !ok {
+		return nil, fmt.Errorf("unknown waveform %q", synthWave)
 	}
-	nowSample++
-	return
+	env := &synth.Envelope
+	if _, err := fmt.Sscanf(synthADSR, "%g,%g,%g,%g", &env.Attack, &env.Decay, &env.Sustain, &env.Release); err != nil {
+		return nil, fmt.Errorf("envelope %q: %v", synthADSR, err)
+	}
+	if synthTempo <= 0 {
+		return nil, fmt.Errorf("tempo %g", synthTempo)
+	}
+	return NewSequencer(synth, steps, 60/synthTempo/4), nil
 }
 
-func NextSampleBytes() (b []byte) {
-	b = make([]byte, 2)
-	binary.BigEndian.PutUint16(b, NextSample())
-	return
+// RenderMix plays the track through the mixer, with everything else it mixes
+// in, to the render file, as fast as it can, until the track ends
+func RenderMix(track Track) error {
+	info := WavInfo{Format: WAVE_FORMAT_PCM, Channels: deviceChannels, Rate: deviceRate, Bits: renderBits}
+	if renderBits == 64 {
+		info.Format = WAVE_FORMAT_IEEE_FLOAT
+	}
+	w, err := CreateWAV(renderFile, info)
+	if err != nil {
+		return err
+	}
+	if streamer, ok := track.(*Streamer); ok {
+		streamer.WaitForDisk(true)
+	}
+	track.Play()
+	if err := mixer.Render(w, track.Done()); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
 }
 
 //export AudioCallback
 func AudioCallback(userdata unsafe.Pointer, stream *C.Uint8, length C.int) {
 	n := int(length)
 	hdr := reflect.SliceHeader{Data: uintptr(unsafe.Pointer(stream)), Len: n, Cap: n}
-	buf := *(*[]C.Uint8)(unsafe.Pointer(&hdr))
-	for i := 0; i < n; i += 2 {
-		b := NextSampleBytes()
-		buf[i] = C.Uint8(b[0])
-		buf[i+1] = C.Uint8(b[1])
-	}
+	mixer.Fill(*(*[]byte)(unsafe.Pointer(&hdr)))
 }
 
 func main() {
-	if err := sdl.Init(sdl.INIT_AUDIO); err != nil {
+	flag.StringVar(&sampleFile, "file", sampleFile, "WAV, FLAC, Ogg Vorbis or MP3 file to play")
+	flag.BoolVar(&viewerOn, "view", viewerOn, "show the waveform and spectrum while playing")
+	flag.StringVar(&recordFile, "record", recordFile, "write what's played to this WAV file")
+	flag.StringVar(&renderFile, "render", renderFile, "write the mix, through the effects and with the synth, to this WAV file, without playing it")
+	flag.IntVar(&renderBits, "bits", renderBits, "bits per sample of -render: 8, 16, 24, 32, or 64 for floating point")
+	flag.IntVar(&deviceRate, "rate", deviceRate, "frames per second of the audio device")
+	flag.IntVar(&deviceChannels, "channels", deviceChannels, "channels of the audio device")
+	flag.StringVar(&deviceFormat, "format", deviceFormat, "samples of the audio device: u8, s16, s32 or f32")
+	flag.StringVar(&fxFile, "fx", fxFile, "sound file to play over the top with keys 1 to 9")
+	flag.BoolVar(&fxLoop, "fxloop", fxLoop, "loop the -fx until X is pressed")
+	flag.StringVar(&effectsFile, "effects", effectsFile, "file of effects to play the mix through, reloaded with R or SIGHUP")
+	flag.StringVar(&synthSteps, "synth", synthSteps, "notes for the synth to play over and over, like 'C4 E4 G4 - . G4 E4 -'")
+	flag.StringVar(&synthWave, "wave", synthWave, "waveform of the synth: sine, square, saw, triangle or noise")
+	flag.Float64Var(&synthTempo, "tempo", synthTempo, "beats per minute of the synth, four steps to a beat")
+	flag.StringVar(&synthADSR, "adsr", synthADSR, "attack, decay, sustain and release of the synth's notes")
+	flag.StringVar(&analysisOutputs, "analysis", analysisOutputs, "send the level, bands and beats of what's played as JSON lines: - for stdout, or udp://host:port, comma separated")
+	flag.BoolVar(&streamOn, "stream", streamOn, "play from disk a piece at a time, without the viewer, for files too long to load")
+	flag.Parse()
+	if renderFile != "" || streamOn {
+		viewerOn = false
+	}
+	stream, ok := audioFormats[deviceFormat]
+	if !ok || deviceRate <= 0 || deviceChannels <= 0 {
+		log.WithFields(log.Fields{
+			"format":   deviceFormat,
+			"rate":     deviceRate,
+			"channels": deviceChannels,
+		}).Fatal("Unknown audio device format")
+	}
+	stream.Channels, stream.Rate = deviceChannels, deviceRate
+	runtime.LockOSThread()
+	var flags uint32 = sdl.INIT_AUDIO
+	if viewerOn {
+		flags |= sdl.INIT_VIDEO
+	}
+	if err := sdl.Init(flags); err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
 		}).Fatal("Cannot init SDL")
 		return
 	}
-    defer func() {
-        if r := recover(); r != nil {
+	var (
+		recorder *Recorder
+		analyser *Analyser
+	)
+	defer func() {
+		if r := recover(); r != nil {
 			stk := debug.Stack()
 			log.WithFields(log.Fields{
-				"stack": string(stk[:]),
+				"stack":   string(stk[:]),
 				"recover": r,
 			}).Warn("Player Recovered")
 
-        }
+		}
 		sdl.PauseAudio(true)
+		if recorder != nil || analyser != nil {
+			sdl.CloseAudio()
+			mixer.Record(nil)
+			mixer.Analyse(nil)
+		}
+		if recorder != nil {
+			if err := recorder.Close(); err != nil {
+				log.WithFields(log.Fields{
+					"file":  recordFile,
+					"error": err,
+				}).Warn("Failed to finish recording")
+			}
+		}
+		if analyser != nil {
+			analyser.Close()
+		}
 		sdl.Quit()
-    }()
+	}()
 
-	loadSpec := LoadSample(sampleFile)
-	if loadSpec != nil {
-		log.WithFields(log.Fields{
-			"spec": loadSpec,
-			}).Info("Loaded")
+	var (
+		err    error
+		track  Track
+		player *Player // if the whole of the sound is loaded
+	)
+	if streamOn {
+		var streamer *Streamer
+		if streamer, err = OpenStream(sampleFile, deviceChannels, deviceRate); err != nil {
+			log.WithFields(log.Fields{
+				"file":  sampleFile,
+				"error": err,
+			}).Fatal("Failed to open")
+		}
+		defer streamer.Close()
+		track = streamer
 	} else {
+		var sound *Sound
+		if sound, err = LoadSound(sampleFile); err != nil {
+			log.WithFields(log.Fields{
+				"file":  sampleFile,
+				"error": err,
+			}).Fatal("Failed to load")
+		}
+		player = NewPlayer(sound)
+		track = player
+	}
+	log.WithFields(log.Fields{
+		"file":     sampleFile,
+		"duration": track.Duration(),
+	}).Info("Loaded")
+
+	mixer = NewMixer(stream)
+	mixer.Add(track)
+	ReloadEffects(mixer.Effects)
+	if synthSteps != "" {
+		sequencer, err := NewSynthSequencer()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"steps": synthSteps,
+				"error": err,
+			}).Fatal("Failed to make synth")
+		}
+		mixer.Add(sequencer)
+	}
+
+	if renderFile != "" {
+		if err := RenderMix(track); err != nil {
+			log.WithFields(log.Fields{
+				"file":  renderFile,
+				"error": err,
+			}).Fatal("Failed to render")
+		}
 		log.WithFields(log.Fields{
-			"file": sampleFile,
+			"file": renderFile,
+		}).Info("Rendered")
+		return
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			ReloadEffects(mixer.Effects)
+		}
+	}()
+
+	var fx *Sound
+	if fxFile != "" {
+		if fx, err = LoadSound(fxFile); err != nil {
+			log.WithFields(log.Fields{
+				"file":  fxFile,
+				"error": err,
 			}).Fatal("Failed to load")
+		}
 	}
 
-	loadSpec.Callback = sdl.AudioCallback(C.AudioCallback)
+	if recordFile != "" {
+		if recorder, err = StartRecorder(recordFile, mixer.Stream()); err != nil {
+			log.WithFields(log.Fields{
+				"file":  recordFile,
+				"error": err,
+			}).Fatal("Failed to record")
+		}
+		mixer.Record(recorder)
+	}
 
-	// playSpec := &sdl.AudioSpec{
-	// 		Freq:     loadSpec.Freq,
-	// 		Format:   loadSpec.Format,
-	// 		Channels: loadSpec.Channels,
-	// 		Samples:  loadSpec.Samples,
-	// 		Callback: sdl.AudioCallback(C.AudioCallback),
-	// 	}
+	if analysisOutputs != "" {
+		outs, err := OpenAnalysisOutputs(analysisOutputs)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"analysis": analysisOutputs,
+				"error":    err,
+			}).Fatal("Failed to open analysis")
+		}
+		analyser = StartAnalyser(deviceChannels, deviceRate, outs)
+		mixer.Analyse(analyser)
+	}
 
-	sdl.OpenAudio(loadSpec, nil)
+	spec := mixer.Spec()
+	spec.Callback = sdl.AudioCallback(C.AudioCallback)
+	if err = sdl.OpenAudio(spec, nil); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("Failed to open audio")
+	}
+	track.Play()
 	sdl.PauseAudio(false)
 
-	time.Sleep(18 * time.Second)
+	if viewerOn {
+		NewViewer(player, mixer, fx).Start()
+		return
+	}
+	<-track.Done()
 }
@@ -0,0 +1,405 @@
+/** Author: Charney Kaye */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// chunk is a RIFF chunk of id around body, padded to an even size
+func chunk(id string, body []byte) []byte {
+	b := append([]byte(id), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(body)))
+	b = append(b, body...)
+	if len(body)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// riff is a RIFF WAVE file of chunks
+func riff(chunks ...[]byte) []byte {
+	body := []byte("WAVE")
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
+
+// fmtBody is the body of a fmt chunk, which is WAVE_FORMAT_EXTENSIBLE around
+// format if extensible
+func fmtBody(format WavFormat, channels, rate, bits int, extensible bool) []byte {
+	b := make([]byte, 16, 40)
+	tag := format
+	if extensible {
+		tag = WAVE_FORMAT_EXTENSIBLE
+	}
+	binary.LittleEndian.PutUint16(b[0:], uint16(tag))
+	binary.LittleEndian.PutUint16(b[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(b[4:], uint32(rate))
+	binary.LittleEndian.PutUint32(b[8:], uint32(rate*channels*bits/8))
+	binary.LittleEndian.PutUint16(b[12:], uint16(channels*bits/8))
+	binary.LittleEndian.PutUint16(b[14:], uint16(bits))
+	if extensible {
+		b = b[:40]
+		binary.LittleEndian.PutUint16(b[16:], 22)
+		binary.LittleEndian.PutUint16(b[18:], uint16(bits))
+		binary.LittleEndian.PutUint32(b[20:], 0x3F)
+		binary.LittleEndian.PutUint16(b[24:], uint16(format))
+		copy(b[26:], wavGUIDSuffix)
+	}
+	return b
+}
+
+// sized is a chunk whose header claims size, whatever the length of body
+func sized(id string, size uint32, body []byte) []byte {
+	b := chunk(id, body)
+	binary.LittleEndian.PutUint32(b[4:], size)
+	return b
+}
+
+var testInfoList = chunk("LIST", []byte("INFOINAM\x06\x00\x00\x00Title\x00IART\x03\x00\x00\x00Me\x00\x00"))
+
+func float32Bytes(v ...float32) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, v)
+	return b.Bytes()
+}
+
+func float64Bytes(v ...float64) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, v)
+	return b.Bytes()
+}
+
+func TestReadWAV(t *testing.T) {
+	formats := []struct {
+		name       string
+		format     WavFormat
+		channels   int
+		bits       int
+		extensible bool
+		data       []byte
+		want       []float32
+	}{
+		{"u8 mono", WAVE_FORMAT_PCM, 1, 8, false, []byte{0, 128, 255, 64}, []float32{-1, 0, 127.0 / 128, -0.5}},
+		{"s16 stereo", WAVE_FORMAT_PCM, 2, 16, false, []byte{0x00, 0x80, 0xFF, 0x7F, 0x00, 0x40, 0x00, 0x00}, []float32{-1, 32767.0 / 32768, 0.5, 0}},
+		{"s24 mono", WAVE_FORMAT_PCM, 1, 24, false, []byte{0x00, 0x00, 0x80, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x40}, []float32{-1, -1.0 / (1 << 23), 0.5}},
+		{"s32 stereo", WAVE_FORMAT_PCM, 2, 32, false, []byte{0, 0, 0, 0x80, 0, 0, 0, 0xC0}, []float32{-1, -0.5}},
+		{"f32 stereo", WAVE_FORMAT_IEEE_FLOAT, 2, 32, false, float32Bytes(0.25, -0.75), []float32{0.25, -0.75}},
+		{"f64 mono", WAVE_FORMAT_IEEE_FLOAT, 1, 64, false, float64Bytes(0.125, 1.5), []float32{0.125, 1.5}},
+		{"extensible s16 quad", WAVE_FORMAT_PCM, 4, 16, true, []byte{0, 0x40, 0, 0xC0, 0, 0, 0, 0x20}, []float32{0.5, -0.5, 0, 0.25}},
+		{"extensible s24 5.1", WAVE_FORMAT_PCM, 6, 24, true, []byte{0, 0, 0x40, 0, 0, 0xC0, 0, 0, 0, 0, 0, 0x20, 0, 0, 0x80, 0xFF, 0xFF, 0x7F}, []float32{0.5, -0.5, 0, 0.25, -1, 8388607.0 / (1 << 23)}},
+		{"extensible f32 mono", WAVE_FORMAT_IEEE_FLOAT, 1, 32, true, float32Bytes(-0.5), []float32{-0.5}},
+		{"extensible f64 stereo", WAVE_FORMAT_IEEE_FLOAT, 2, 64, true, float64Bytes(0.5, -0.25), []float32{0.5, -0.25}},
+	}
+	layouts := []struct {
+		name     string
+		list     string // "before" or "after" the data, or neither
+		dataSize func(data []byte) uint32
+	}{
+		{"no tags", "", func(data []byte) uint32 { return uint32(len(data)) }},
+		{"tags before data", "before", func(data []byte) uint32 { return uint32(len(data)) }},
+		{"tags after data", "after", func(data []byte) uint32 { return uint32(len(data)) }},
+		{"unpatched size", "before", func([]byte) uint32 { return math.MaxUint32 }},
+		{"zero size", "before", func([]byte) uint32 { return 0 }},
+	}
+	for _, f := range formats {
+		for _, l := range layouts {
+			chunks := [][]byte{chunk("junk", []byte{1, 2, 3}), chunk("fmt ", fmtBody(f.format, f.channels, 22050, f.bits, f.extensible))}
+			if l.list == "before" {
+				chunks = append(chunks, testInfoList)
+			}
+			chunks = append(chunks, sized("data", l.dataSize(f.data), f.data))
+			if l.list == "after" {
+				chunks = append(chunks, testInfoList)
+			}
+			s, err := ReadWAV(bytes.NewReader(riff(chunks...)))
+			if err != nil {
+				t.Errorf("%s, %s: %v", f.name, l.name, err)
+				continue
+			}
+			if s.Format != f.format || s.Channels != f.channels || s.Rate != 22050 || s.Bits != f.bits || s.Extensible != f.extensible {
+				t.Errorf("%s, %s: read as %+v", f.name, l.name, s.WavInfo)
+			}
+			if s.Frames != len(f.want)/f.channels {
+				t.Errorf("%s, %s: %d frames, want %d", f.name, l.name, s.Frames, len(f.want)/f.channels)
+			}
+			if l.list != "" && (s.Tags["INAM"] != "Title" || s.Tags["IART"] != "Me") {
+				t.Errorf("%s, %s: tags %v", f.name, l.name, s.Tags)
+			}
+			if len(s.Samples) != len(f.want) {
+				t.Errorf("%s, %s: samples %v, want %v", f.name, l.name, s.Samples, f.want)
+				continue
+			}
+			for i := range f.want {
+				if s.Samples[i] != f.want[i] {
+					t.Errorf("%s, %s: sample %d is %v, want %v", f.name, l.name, i, s.Samples[i], f.want[i])
+				}
+			}
+		}
+	}
+}
+
+func TestReadWAVRoundTrip(t *testing.T) {
+	in := []float32{0, 0.5, -0.5, 0.25, -1, 0.75, -0.125, 0.0625, 0.3, -0.9, 0.001, -0.001, 0.99, -0.6, 0.2, -0.2, 0.45, -0.05, 0.6, 0.1, -0.3, 0.8, -0.8, 0.05}
+	for _, format := range []struct {
+		format WavFormat
+		bits   int
+	}{{WAVE_FORMAT_PCM, 8}, {WAVE_FORMAT_PCM, 16}, {WAVE_FORMAT_PCM, 24}, {WAVE_FORMAT_PCM, 32}, {WAVE_FORMAT_IEEE_FLOAT, 32}, {WAVE_FORMAT_IEEE_FLOAT, 64}} {
+		for _, channels := range []int{1, 2, 3, 6, 8} {
+			info := WavInfo{Format: format.format, Channels: channels, Rate: 48000, Bits: format.bits}
+			samples := in[:len(in)/channels*channels]
+			data := make([]byte, len(samples)*info.Bits/8)
+			info.Encode(samples, data)
+			extensible := channels > 2
+			s, err := ReadWAV(bytes.NewReader(riff(chunk("fmt ", fmtBody(info.Format, channels, info.Rate, info.Bits, extensible)), chunk("data", data))))
+			if err != nil {
+				t.Errorf("%d-bit %#x, %d channels: %v", info.Bits, info.Format, channels, err)
+				continue
+			}
+			if s.Frames != len(samples)/channels || s.Extensible != extensible {
+				t.Errorf("%d-bit %#x, %d channels: read as %+v", info.Bits, info.Format, channels, s.WavInfo)
+				continue
+			}
+			// integers are within half a step of what was encoded
+			tolerance := 0.0
+			if info.Format == WAVE_FORMAT_PCM {
+				tolerance = 0.5 / float64(int64(1)<<uint(info.Bits-1))
+			}
+			for i, want := range samples {
+				if d := math.Abs(float64(s.Samples[i] - want)); d > tolerance+1e-9 {
+					t.Errorf("%d-bit %#x, %d channels: sample %d is %v, want %v", info.Bits, info.Format, channels, i, s.Samples[i], want)
+				}
+			}
+		}
+	}
+}
+
+func TestReadWAVTruncated(t *testing.T) {
+	fmtChunk := chunk("fmt ", fmtBody(WAVE_FORMAT_PCM, 2, 8000, 16, false))
+	data := []byte{0, 0x40, 0, 0xC0, 0, 0x20, 0, 0xE0}
+	for _, c := range []struct {
+		name   string
+		file   []byte
+		frames int
+	}{
+		{"data cut short", riff(fmtChunk, sized("data", 100, data)), 2},
+		{"data cut mid-frame", riff(fmtChunk, sized("data", 100, data[:6])), 1},
+		{"data of no frames", riff(fmtChunk, sized("data", 100, data[:2])), 0},
+		{"tags cut short after data", riff(fmtChunk, chunk("data", data), sized("LIST", 100, []byte("INFOINAM"))), 2},
+		{"huge chunk after data", riff(fmtChunk, chunk("data", data), sized("LIST", math.MaxUint32, nil)), 2},
+	} {
+		s, err := ReadWAV(bytes.NewReader(c.file))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if s.Frames != c.frames || len(s.Samples) != c.frames*2 {
+			t.Errorf("%s: %d frames of %d samples, want %d frames", c.name, s.Frames, len(s.Samples), c.frames)
+		}
+	}
+}
+
+func TestReadWAVMalformed(t *testing.T) {
+	fmtChunk := chunk("fmt ", fmtBody(WAVE_FORMAT_PCM, 1, 8000, 16, false))
+	data := chunk("data", []byte{0, 0})
+	extensible := fmtBody(WAVE_FORMAT_PCM, 4, 8000, 16, true)
+	badGUID := append([]byte(nil), extensible...)
+	badGUID[30] ^= 0xFF
+	for _, c := range []struct {
+		name string
+		file []byte
+	}{
+		{"empty", nil},
+		{"only RIFF", []byte("RIFF")},
+		{"RIFX", append([]byte("RIFX\x04\x00\x00\x00WAVE"), fmtChunk...)},
+		{"not WAVE", append([]byte("RIFF\x04\x00\x00\x00AVI "), fmtChunk...)},
+		{"no chunks", riff()},
+		{"no fmt", riff(data)},
+		{"no data", riff(fmtChunk)},
+		{"chunk header cut short", append(riff(fmtChunk), 'd', 'a')},
+		{"fmt cut short", riff(sized("fmt ", 16, fmtBody(WAVE_FORMAT_PCM, 1, 8000, 16, false)[:10]))},
+		{"fmt too short", riff(chunk("fmt ", fmtBody(WAVE_FORMAT_PCM, 1, 8000, 16, false)[:14]), data)},
+		{"fmt too long", riff(sized("fmt ", math.MaxUint32, nil), data)},
+		{"LIST cut short", riff(fmtChunk, sized("LIST", 40, []byte("INFO")))},
+		{"LIST too long", riff(fmtChunk, sized("LIST", math.MaxUint32, nil), data)},
+		{"unknown chunk cut short", riff(fmtChunk, sized("junk", 100, nil))},
+		{"unknown chunk too long", riff(fmtChunk, sized("junk", math.MaxUint32, nil))},
+		{"no channels", riff(chunk("fmt ", fmtBody(WAVE_FORMAT_PCM, 0, 8000, 16, false)), data)},
+		{"no rate", riff(chunk("fmt ", fmtBody(WAVE_FORMAT_PCM, 1, 0, 16, false)), data)},
+		{"no bits", riff(chunk("fmt ", fmtBody(WAVE_FORMAT_PCM, 1, 8000, 0, false)), data)},
+		{"12-bit", riff(chunk("fmt ", fmtBody(WAVE_FORMAT_PCM, 1, 8000, 12, false)), data)},
+		{"16-bit float", riff(chunk("fmt ", fmtBody(WAVE_FORMAT_IEEE_FLOAT, 1, 8000, 16, false)), data)},
+		{"ADPCM", riff(chunk("fmt ", fmtBody(2, 1, 8000, 4, false)), data)},
+		{"extensible cut short", riff(chunk("fmt ", extensible[:30]), data)},
+		{"extensible of another GUID", riff(chunk("fmt ", badGUID), data)},
+	} {
+		if _, err := ReadWAV(bytes.NewReader(c.file)); err == nil {
+			t.Errorf("%s: read without error", c.name)
+		}
+	}
+}
+
+func TestWavWriterRoundTrip(t *testing.T) {
+	in := []float32{0, 0.5, -0.5, 1, -1, 0.25, 2, -2, 0.001, -0.75, 0.125, 0.999, -0.3, 0.7, -0.01, 0.04, 0.6, -0.6, 0.33, -0.33, 0.05, 0.9, -0.9, 0}
+	for _, format := range []struct {
+		format WavFormat
+		bits   int
+	}{{WAVE_FORMAT_PCM, 8}, {WAVE_FORMAT_PCM, 16}, {WAVE_FORMAT_PCM, 24}, {WAVE_FORMAT_PCM, 32}, {WAVE_FORMAT_IEEE_FLOAT, 32}, {WAVE_FORMAT_IEEE_FLOAT, 64}} {
+		for _, channels := range []int{1, 2, 3, 6, 8, 12} {
+			info := WavInfo{Format: format.format, Channels: channels, Rate: 48000, Bits: format.bits}
+			samples := in[:len(in)/channels*channels]
+			// unseekable, so the sizes are left unpatched
+			var out bytes.Buffer
+			w, err := NewWavWriter(&out, info)
+			if err != nil {
+				t.Fatalf("%d-bit %#x, %d channels: %v", info.Bits, info.Format, channels, err)
+			}
+			// a frame at a time, then the rest
+			if err := w.WriteSamples(samples[:channels]); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteSamples(samples[channels:]); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			s, err := ReadWAV(&out)
+			if err != nil {
+				t.Fatalf("%d-bit %#x, %d channels: %v", info.Bits, info.Format, channels, err)
+			}
+			mask := uint32(0)
+			if channels > 2 || info.Bits > 16 {
+				if channels <= len(wavChannelMasks) {
+					mask = wavChannelMasks[channels-1]
+				}
+			}
+			if s.Format != info.Format || s.Bits != info.Bits || s.Channels != channels || s.Frames != len(samples)/channels || s.Extensible != (channels > 2 || info.Bits > 16) || s.ChannelMask != mask {
+				t.Errorf("%d-bit %#x, %d channels: read as %+v", info.Bits, info.Format, channels, s.WavInfo)
+				continue
+			}
+			// integers are clipped, then within half a step
+			step := 0.0
+			if info.Format == WAVE_FORMAT_PCM {
+				step = 1 / float64(int64(1)<<uint(info.Bits-1))
+			}
+			for i, v := range samples {
+				want := float64(v)
+				if info.Format == WAVE_FORMAT_PCM {
+					want = math.Max(-1, math.Min(want, 1-step))
+				}
+				if d := math.Abs(float64(s.Samples[i]) - want); d > step/2+1e-7 {
+					t.Errorf("%d-bit %#x, %d channels: sample %d is %v, want %v", info.Bits, info.Format, channels, i, s.Samples[i], want)
+				}
+			}
+		}
+	}
+}
+
+func TestWavWriterHeader(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		info    WavInfo
+		samples []float32
+		seeks   bool
+		want    string
+	}{
+		{
+			"16-bit stereo",
+			WavInfo{Format: WAVE_FORMAT_PCM, Channels: 2, Rate: 44100, Bits: 16},
+			[]float32{0.5, -0.5, 0, 1},
+			true,
+			"RIFF\x2c\x00\x00\x00WAVE" +
+				"fmt \x10\x00\x00\x00\x01\x00\x02\x00\x44\xac\x00\x00\x10\xb1\x02\x00\x04\x00\x10\x00" +
+				"data\x08\x00\x00\x00\x00\x40\x00\xc0\x00\x00\xff\x7f",
+		},
+		{
+			"8-bit mono, padded",
+			WavInfo{Format: WAVE_FORMAT_PCM, Channels: 1, Rate: 8000, Bits: 8},
+			[]float32{0, 0.5, -1},
+			true,
+			"RIFF\x28\x00\x00\x00WAVE" +
+				"fmt \x10\x00\x00\x00\x01\x00\x01\x00\x40\x1f\x00\x00\x40\x1f\x00\x00\x01\x00\x08\x00" +
+				"data\x03\x00\x00\x00\x80\xc0\x00\x00",
+		},
+		{
+			"24-bit 5.1, extensible",
+			WavInfo{Format: WAVE_FORMAT_PCM, Channels: 6, Rate: 48000, Bits: 24},
+			[]float32{0, 0, 0, 0, 0, 0.5},
+			true,
+			"RIFF\x4e\x00\x00\x00WAVE" +
+				"fmt \x28\x00\x00\x00\xfe\xff\x06\x00\x80\xbb\x00\x00\x00\x2f\x0d\x00\x12\x00\x18\x00" +
+				"\x16\x00\x18\x00\x3f\x00\x00\x00\x01\x00" + wavGUIDSuffix +
+				"data\x12\x00\x00\x00" + "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x40",
+		},
+		{
+			"32-bit float mono, unpatched",
+			WavInfo{Format: WAVE_FORMAT_IEEE_FLOAT, Channels: 1, Rate: 22050, Bits: 32},
+			[]float32{-0.5},
+			false,
+			"RIFF\xff\xff\xff\xffWAVE" +
+				"fmt \x28\x00\x00\x00\xfe\xff\x01\x00\x22\x56\x00\x00\x88\x58\x01\x00\x04\x00\x20\x00" +
+				"\x16\x00\x20\x00\x04\x00\x00\x00\x03\x00" + wavGUIDSuffix +
+				"data\xff\xff\xff\xff\x00\x00\x00\xbf",
+		},
+	} {
+		var got []byte
+		if c.seeks {
+			path := filepath.Join(t.TempDir(), "header.wav")
+			w, err := CreateWAV(path, c.info)
+			if err != nil {
+				t.Fatal(c.name, err)
+			}
+			w.WriteSamples(c.samples)
+			if err := w.Close(); err != nil {
+				t.Fatal(c.name, err)
+			}
+			if got, err = os.ReadFile(path); err != nil {
+				t.Fatal(c.name, err)
+			}
+		} else {
+			var out bytes.Buffer
+			w, err := NewWavWriter(&out, c.info)
+			if err != nil {
+				t.Fatal(c.name, err)
+			}
+			w.WriteSamples(c.samples)
+			w.Close()
+			got = out.Bytes()
+		}
+		if string(got) != c.want {
+			t.Errorf("%s:\n got % x\nwant % x", c.name, got, []byte(c.want))
+		}
+	}
+}
+
+func TestWavWriterInvalid(t *testing.T) {
+	for _, info := range []WavInfo{
+		{Format: WAVE_FORMAT_PCM, Channels: 0, Rate: 44100, Bits: 16},
+		{Format: WAVE_FORMAT_PCM, Channels: 0, Rate: 44100, Bits: 24},
+		{Format: WAVE_FORMAT_PCM, Channels: -1, Rate: 44100, Bits: 16},
+		{Format: WAVE_FORMAT_PCM, Channels: 1 << 16, Rate: 44100, Bits: 16},
+		{Format: WAVE_FORMAT_PCM, Channels: 1<<16 + 1, Rate: 44100, Bits: 16},
+		{Format: WAVE_FORMAT_PCM, Channels: 2, Rate: 0, Bits: 16},
+		{Format: WAVE_FORMAT_PCM, Channels: 2, Rate: 44100, Bits: 0},
+		{Format: WAVE_FORMAT_PCM, Channels: 2, Rate: 44100, Bits: 12},
+		{Format: WAVE_FORMAT_PCM, Channels: 2, Rate: 44100, Bits: 64},
+		{Format: WAVE_FORMAT_IEEE_FLOAT, Channels: 2, Rate: 44100, Bits: 16},
+		{Format: 0, Channels: 2, Rate: 44100, Bits: 16},
+	} {
+		var out bytes.Buffer
+		if _, err := NewWavWriter(&out, info); err == nil {
+			t.Errorf("%+v: written without error", info)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%+v: wrote %d bytes", info, out.Len())
+		}
+	}
+}